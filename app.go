@@ -3,11 +3,15 @@ package ginny
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/google/wire"
+	"github.com/goriller/ginny-util/graceful"
 	"github.com/goriller/ginny/config"
 	"github.com/goriller/ginny/logger"
 	"github.com/goriller/ginny/server"
+	"github.com/goriller/ginny/server/admin"
+	"github.com/goriller/ginny/worker"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -46,7 +50,13 @@ type Application struct {
 	Server  *server.Server
 	Ctx     context.Context
 
-	regFunc RegistrarFunc
+	regFunc    RegistrarFunc
+	components []*component
+	onStart    []Hook
+	onReady    []Hook
+	onStop     []Hook
+	workers    *worker.Manager
+	stopOnce   sync.Once
 }
 
 // Option
@@ -56,15 +66,25 @@ type Option struct {
 	GrpcAddr    string
 	HttpAddr    string
 	MetricsAddr string
+	// Server the `server` section of config
+	Server *server.Config `mapstructure:"-"`
+
+	v *viper.Viper
 }
 
 // NewOption
 func NewOption(v *viper.Viper) (*Option, error) {
 	var err error
-	o := new(Option)
+	o := &Option{v: v}
 	if err = v.UnmarshalKey("app", o); err != nil {
 		return nil, errors.Wrap(err, "unmarshal app option error")
 	}
+	if v.IsSet("server") {
+		o.Server = new(server.Config)
+		if err = v.UnmarshalKey("server", o.Server); err != nil {
+			return nil, errors.Wrap(err, "unmarshal server option error")
+		}
+	}
 
 	return o, nil
 }
@@ -87,6 +107,15 @@ func NewApp(
 	opt := []server.Option{
 		server.WithGrpcAddr(option.GrpcAddr),
 	}
+	adminOpts := []admin.Option{admin.WithBuildInfo(option.Name, option.Version)}
+	if option.v != nil {
+		v := option.v
+		adminOpts = append(adminOpts, admin.WithHandler("/config", admin.JSONHandler(func() interface{} {
+			return config.Dump(v)
+		})))
+	}
+	// the options from code take precedence over config
+	opts = append(append(option.Server.Options(), server.WithAdmin(adminOpts...)), opts...)
 	if option.HttpAddr != "" {
 		opts = append(opts,
 			server.WithHttpAddr(option.HttpAddr),
@@ -103,16 +132,43 @@ func NewApp(
 	return app, nil
 }
 
-// Start
+// Start run the OnStart hooks, start the components in order and the workers, then start the servers.
 func (a *Application) Start(ctx context.Context) error {
 	if err := a.regFunc(a); err != nil {
 		return err
 	}
+	if err := a.runHooks(ctx, a.onStart, true); err != nil {
+		return errors.Wrap(err, "run start hook error")
+	}
+	if err := a.startComponents(ctx); err != nil {
+		return err
+	}
+	if err := a.startWorkers(ctx); err != nil {
+		_ = a.stopComponents(ctx, a.components)
+		return err
+	}
+	// closers run in reverse order, so the server is closed before the application
+	graceful.AddCloser(a.Stop)
+	go a.ready(ctx)
 	a.Server.Start(ctx)
 	return nil
 }
 
-// Stop
+// Stop close the servers, stop the workers, run the OnStop hooks, then stop the components in reverse order.
+// It is safe to call Stop more than once, only the first call takes effect.
 func (a *Application) Stop(ctx context.Context) error {
-	return a.Server.Close(ctx)
+	var err error
+	a.stopOnce.Do(func() {
+		err = a.Server.Close(ctx)
+		if workerErr := a.stopWorkers(ctx); err == nil {
+			err = workerErr
+		}
+		if hookErr := a.runHooks(ctx, a.onStop, false); err == nil {
+			err = hookErr
+		}
+		if compErr := a.stopComponents(ctx, a.components); err == nil {
+			err = compErr
+		}
+	})
+	return err
 }
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestMemoryCacheMany(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{Shards: 4})
+	defer c.Close()
+
+	items := make(map[string]interface{})
+	keys := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("k%d", i)
+		items[key] = i
+		keys = append(keys, key)
+	}
+	if err := c.SetMany(ctx, items, 0); err != nil {
+		t.Fatal(err)
+	}
+	values, err := c.GetMany(ctx, append(keys, "missing"))
+	if err != nil || values.Len() != 20 || values.Has("missing") {
+		t.Fatalf("unexpected values %v %v", values.Len(), err)
+	}
+	var n int
+	if err := values.Decode("k7", &n); err != nil || n != 7 {
+		t.Errorf("decode k7: %v %v", n, err)
+	}
+	if s := c.Stats(); s.Sets != 20 || s.Hits != 20 || s.Misses != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	_ = c.DeleteMany(ctx, keys[:10])
+	if s := c.Stats(); s.TotalKeys != 10 || s.Deletes != 10 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestMemoryCachePrefixAndTags(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{Shards: 4})
+	defer c.Close()
+
+	_ = c.Set(ctx, "user:42:profile", 1, 0, WithTags("user:42"))
+	_ = c.SetMany(ctx, map[string]interface{}{"user:42:orders": 2, "user:43:orders": 3}, 0, WithTags("orders"))
+	_ = c.Set(ctx, "user:43:profile", 4, 0, WithTags("user:43"))
+	_ = c.Set(ctx, "team:1", 5, 0, WithTags("user:42"))
+
+	if err := c.InvalidateTags(ctx, "user:42"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{
+		"user:42:profile": false, "team:1": false, "user:42:orders": true, "user:43:profile": true,
+	} {
+		if got := c.Exists(ctx, key); got != want {
+			t.Errorf("exists %s = %v, want %v", key, got, want)
+		}
+	}
+
+	// 重新设置时替换标签
+	_ = c.Set(ctx, "user:43:orders", 3, 0)
+	_ = c.InvalidateTags(ctx, "orders")
+	if c.Exists(ctx, "user:42:orders") || !c.Exists(ctx, "user:43:orders") {
+		t.Error("expect the tags to be replaced")
+	}
+
+	_ = c.DeletePrefix(ctx, "user:43:")
+	if s := c.Stats(); s.TotalKeys != 0 || s.MemoryUsage != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	for _, sh := range c.store.shards {
+		if len(sh.tags) != 0 {
+			t.Errorf("expect the tag index to be empty, got %v", sh.tags)
+		}
+	}
+}
+
+func TestMultiLevelMany(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	bus := NewMemoryBus()
+	a, b := newTestMultiLevel(t, f, bus), newTestMultiLevel(t, f, bus)
+
+	_ = a.SetMany(ctx, map[string]interface{}{"user:1": 1, "user:2": 2}, 0, WithTags("users"))
+	values, err := b.GetMany(ctx, []string{"user:1", "user:2", "user:3"})
+	if err != nil || values.Len() != 2 {
+		t.Fatalf("unexpected values %v %v", values.Keys(), err)
+	}
+	if !b.l1.Exists(ctx, "user:1") {
+		t.Error("expect read through to fill L1")
+	}
+	var n int
+	if err := values.Decode("user:2", &n); err != nil || n != 2 {
+		t.Errorf("decode: %v %v", n, err)
+	}
+
+	_ = a.DeletePrefix(ctx, "user:1")
+	if b.l1.Exists(ctx, "user:1") || !b.l1.Exists(ctx, "user:2") {
+		t.Error("expect the prefix to be invalidated on b")
+	}
+	_ = a.InvalidateTags(ctx, "users")
+	if b.Exists(ctx, "user:2") {
+		t.Error("expect the tag to be invalidated on b")
+	}
+}
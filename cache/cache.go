@@ -2,347 +2,147 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"sync"
 	"time"
 )
 
 var (
 	ErrKeyNotFound = errors.New("key not found")
 	ErrCacheClosed = errors.New("cache is closed")
+	// ErrValueTooLarge 条目大于一个分片的容量，无法写入
+	ErrValueTooLarge = errors.New("cache: value is larger than the shard capacity")
+	// ErrFlushDisabled 没有Prefix且未开启FlushDB时，Clear会清空整个数据库，拒绝执行
+	ErrFlushDisabled = errors.New("cache: clear without prefix flushes the whole db, set FlushDB to allow it")
 )
 
 // Cache 缓存接口
 type Cache interface {
 	Get(ctx context.Context, key string, dest interface{}) error
-	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// Set 设置缓存值，ttl为0时不过期，可以通过WithTags设置标签
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) error
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) bool
 	Clear(ctx context.Context) error
-	Stats() CacheStats
-	Close() error
-}
 
-// CacheStats 缓存统计信息
-type CacheStats struct {
-	Hits        int64   `json:"hits"`
-	Misses      int64   `json:"misses"`
-	Sets        int64   `json:"sets"`
-	Deletes     int64   `json:"deletes"`
-	Errors      int64   `json:"errors"`
-	HitRatio    float64 `json:"hit_ratio"`
-	TotalKeys   int     `json:"total_keys"`
-	MemoryUsage int64   `json:"memory_usage_bytes"`
-}
+	// GetMany 批量获取，结果只包含存在的key
+	GetMany(ctx context.Context, keys []string) (Values, error)
+	// SetMany 以相同的ttl和标签批量设置
+	SetMany(ctx context.Context, items map[string]interface{}, ttl time.Duration, opts ...SetOption) error
+	// DeleteMany 批量删除
+	DeleteMany(ctx context.Context, keys []string) error
+	// DeletePrefix 删除以prefix开头的key
+	DeletePrefix(ctx context.Context, prefix string) error
+	// InvalidateTags 删除带有任一标签的key
+	InvalidateTags(ctx context.Context, tags ...string) error
 
-// cacheItem 缓存项
-type cacheItem struct {
-	Value     interface{} `json:"value"`
-	ExpiredAt time.Time   `json:"expired_at"`
-	CreatedAt time.Time   `json:"created_at"`
-	Size      int64       `json:"size"`
+	Stats() CacheStats
+	Close() error
 }
 
-// isExpired 检查是否过期
-func (item *cacheItem) isExpired() bool {
-	return !item.ExpiredAt.IsZero() && time.Now().After(item.ExpiredAt)
+// KeyInfo key的信息
+type KeyInfo struct {
+	Key  string   `json:"key"`
+	Size int64    `json:"size,omitempty"`
+	TTL  string   `json:"ttl,omitempty"` // 剩余的过期时间，为空表示不过期
+	Tags []string `json:"tags,omitempty"`
 }
 
-// MemoryCache 内存缓存实现
-type MemoryCache struct {
-	mu      sync.RWMutex
-	items   map[string]*cacheItem
-	stats   CacheStats
-	closed  bool
-	maxSize int64 // 最大内存使用量（字节）
-
-	// 清理相关
-	cleanupInterval time.Duration
-	stopCleanup     chan struct{}
+// Inspector 可以按前缀列出key的缓存，用于管理接口
+type Inspector interface {
+	// Keys 列出以prefix开头的key，最多limit个
+	Keys(ctx context.Context, prefix string, limit int) ([]KeyInfo, error)
 }
 
-// MemoryCacheConfig 内存缓存配置
-type MemoryCacheConfig struct {
-	MaxSize         int64         // 最大内存使用量（字节）
-	CleanupInterval time.Duration // 清理间隔
-}
-
-// NewMemoryCache 创建内存缓存
-func NewMemoryCache(config MemoryCacheConfig) *MemoryCache {
-	if config.MaxSize <= 0 {
-		config.MaxSize = 100 * 1024 * 1024 // 默认100MB
-	}
-	if config.CleanupInterval <= 0 {
-		config.CleanupInterval = 5 * time.Minute
-	}
-
-	cache := &MemoryCache{
-		items:           make(map[string]*cacheItem),
-		maxSize:         config.MaxSize,
-		cleanupInterval: config.CleanupInterval,
-		stopCleanup:     make(chan struct{}),
-	}
-
-	// 启动清理协程
-	go cache.cleaner()
-
-	return cache
+// TTLReader 可以读取key剩余TTL的缓存，MultiLevel回填L1时不超过L2中的剩余TTL
+type TTLReader interface {
+	// TTLs 返回存在的key的剩余TTL，不过期的key为0，不存在的key不在结果中
+	TTLs(ctx context.Context, keys []string) (map[string]time.Duration, error)
 }
 
-// Get 获取缓存值
-func (c *MemoryCache) Get(ctx context.Context, key string, dest interface{}) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.closed {
-		return ErrCacheClosed
-	}
-
-	item, exists := c.items[key]
-	if !exists {
-		c.stats.Misses++
-		return ErrKeyNotFound
-	}
-
-	if item.isExpired() {
-		c.stats.Misses++
-		// 延迟删除，避免在读锁中修改
-		go func() {
-			c.Delete(ctx, key)
-		}()
-		return ErrKeyNotFound
-	}
-
-	c.stats.Hits++
-
-	// 反序列化到目标对象
-	if err := c.deserialize(item.Value, dest); err != nil {
-		c.stats.Errors++
-		return err
-	}
-
-	return nil
+// setOptions
+type setOptions struct {
+	tags []string
 }
 
-// Set 设置缓存值
-func (c *MemoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.closed {
-		return ErrCacheClosed
-	}
-
-	// 序列化值
-	serializedValue, size, err := c.serialize(value)
-	if err != nil {
-		c.stats.Errors++
-		return err
-	}
-
-	// 检查内存限制
-	if c.stats.MemoryUsage+size > c.maxSize {
-		// 执行LRU淘汰
-		c.evictLRU(size)
-	}
-
-	var expiredAt time.Time
-	if ttl > 0 {
-		expiredAt = time.Now().Add(ttl)
-	}
-
-	// 如果key已存在，先减去旧的大小
-	if oldItem, exists := c.items[key]; exists {
-		c.stats.MemoryUsage -= oldItem.Size
-	}
+// SetOption the option of Set and SetMany
+type SetOption func(*setOptions)
 
-	item := &cacheItem{
-		Value:     serializedValue,
-		ExpiredAt: expiredAt,
-		CreatedAt: time.Now(),
-		Size:      size,
+// WithTags tag the keys, such as "user:42", which are deleted by InvalidateTags together
+func WithTags(tags ...string) SetOption {
+	return func(o *setOptions) {
+		o.tags = append(o.tags, tags...)
 	}
-
-	c.items[key] = item
-	c.stats.MemoryUsage += size
-	c.stats.Sets++
-	c.stats.TotalKeys = len(c.items)
-
-	return nil
 }
 
-// Delete 删除缓存值
-func (c *MemoryCache) Delete(ctx context.Context, key string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.closed {
-		return ErrCacheClosed
-	}
-
-	if item, exists := c.items[key]; exists {
-		delete(c.items, key)
-		c.stats.MemoryUsage -= item.Size
-		c.stats.Deletes++
-		c.stats.TotalKeys = len(c.items)
+// newSetOptions
+func newSetOptions(opts []SetOption) setOptions {
+	var o setOptions
+	for _, opt := range opts {
+		opt(&o)
 	}
-
-	return nil
+	return o
 }
 
-// Exists 检查key是否存在
-func (c *MemoryCache) Exists(ctx context.Context, key string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.closed {
-		return false
-	}
-
-	item, exists := c.items[key]
-	if !exists {
-		return false
-	}
-
-	return !item.isExpired()
+// CacheStats 缓存统计信息
+type CacheStats struct {
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	Sets        int64   `json:"sets"`
+	Deletes     int64   `json:"deletes"`
+	Errors      int64   `json:"errors"`
+	Evictions   int64   `json:"evictions"`
+	Expirations int64   `json:"expirations"`
+	HitRatio    float64 `json:"hit_ratio"`
+	TotalKeys   int     `json:"total_keys"`
+	MemoryUsage int64   `json:"memory_usage_bytes"`
 }
 
-// Clear 清空缓存
-func (c *MemoryCache) Clear(ctx context.Context) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.closed {
-		return ErrCacheClosed
-	}
-
-	c.items = make(map[string]*cacheItem)
-	c.stats.MemoryUsage = 0
-	c.stats.TotalKeys = 0
-
-	return nil
+// Values 批量获取的结果，按key解码
+type Values struct {
+	items map[string]encoded
 }
 
-// Stats 获取统计信息
-func (c *MemoryCache) Stats() CacheStats {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	stats := c.stats
-	total := stats.Hits + stats.Misses
-	if total > 0 {
-		stats.HitRatio = float64(stats.Hits) / float64(total)
-	}
-	stats.TotalKeys = len(c.items)
-
-	return stats
+// encoded 编码的值
+type encoded struct {
+	data  []byte
+	codec Codec
 }
 
-// Close 关闭缓存
-func (c *MemoryCache) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.closed {
-		return nil
-	}
-
-	c.closed = true
-	close(c.stopCleanup)
-	c.items = nil
-
-	return nil
+// newValues
+func newValues(n int) Values {
+	return Values{items: make(map[string]encoded, n)}
 }
 
-// serialize 序列化值
-func (c *MemoryCache) serialize(value interface{}) (interface{}, int64, error) {
-	// 对于简单类型，直接存储
-	switch v := value.(type) {
-	case string, int, int64, float64, bool:
-		return v, int64(64), nil // 估算大小
-	default:
-		// 复杂类型使用JSON序列化
-		data, err := json.Marshal(value)
-		if err != nil {
-			return nil, 0, err
-		}
-		return string(data), int64(len(data)), nil
-	}
+// add
+func (v Values) add(key string, data []byte, codec Codec) {
+	v.items[key] = encoded{data: data, codec: codec}
 }
 
-// deserialize 反序列化值
-func (c *MemoryCache) deserialize(value interface{}, dest interface{}) error {
-	switch v := value.(type) {
-	case string:
-		// 尝试JSON反序列化
-		return json.Unmarshal([]byte(v), dest)
-	default:
-		// 直接赋值
-		return json.Unmarshal([]byte(`"`+value.(string)+`"`), dest)
-	}
+// Len 存在的key数
+func (v Values) Len() int {
+	return len(v.items)
 }
 
-// evictLRU LRU淘汰策略
-func (c *MemoryCache) evictLRU(neededSize int64) {
-	// 简单的LRU实现：找到最老的项目并删除
-	for c.stats.MemoryUsage+neededSize > c.maxSize && len(c.items) > 0 {
-		var oldestKey string
-		var oldestTime time.Time
-
-		for key, item := range c.items {
-			if oldestTime.IsZero() || item.CreatedAt.Before(oldestTime) {
-				oldestKey = key
-				oldestTime = item.CreatedAt
-			}
-		}
-
-		if oldestKey != "" {
-			if item := c.items[oldestKey]; item != nil {
-				delete(c.items, oldestKey)
-				c.stats.MemoryUsage -= item.Size
-			}
-		}
-	}
+// Has 检查key是否存在
+func (v Values) Has(key string) bool {
+	_, ok := v.items[key]
+	return ok
 }
 
-// cleaner 清理过期项目
-func (c *MemoryCache) cleaner() {
-	ticker := time.NewTicker(c.cleanupInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			c.cleanupExpired()
-		case <-c.stopCleanup:
-			return
-		}
+// Keys 存在的key
+func (v Values) Keys() []string {
+	keys := make([]string, 0, len(v.items))
+	for key := range v.items {
+		keys = append(keys, key)
 	}
+	return keys
 }
 
-// cleanupExpired 清理过期项目
-func (c *MemoryCache) cleanupExpired() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.closed {
-		return
-	}
-
-	var expiredKeys []string
-	for key, item := range c.items {
-		if item.isExpired() {
-			expiredKeys = append(expiredKeys, key)
-		}
-	}
-
-	for _, key := range expiredKeys {
-		if item := c.items[key]; item != nil {
-			delete(c.items, key)
-			c.stats.MemoryUsage -= item.Size
-		}
+// Decode 解码key的值到dest，不存在时返回ErrKeyNotFound
+func (v Values) Decode(key string, dest interface{}) error {
+	e, ok := v.items[key]
+	if !ok {
+		return ErrKeyNotFound
 	}
-
-	c.stats.TotalKeys = len(c.items)
+	return e.codec.Unmarshal(e.data, dest)
 }
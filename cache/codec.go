@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 值的编码，用于MemoryCache和远程缓存等按字节存储的Cache
+type Codec interface {
+	// Name 编码名称
+	Name() string
+	// Marshal 编码
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 解码到v，v必须是指针
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSONCodec encoding/json编码，默认的编码
+	JSONCodec Codec = jsonCodec{}
+	// ProtoCodec protobuf编码，值必须是proto.Message
+	ProtoCodec Codec = protoCodec{}
+	// GobCodec encoding/gob编码，接口类型的值需要先gob.Register
+	GobCodec Codec = gobCodec{}
+)
+
+// jsonCodec
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// protoCodec
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cache: proto codec can not marshal %T", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cache: proto codec can not unmarshal to %T", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// gobCodec
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMemoryCachePrimitives(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+
+	_ = c.Set(ctx, "int", 42, 0)
+	_ = c.Set(ctx, "bool", true, 0)
+	_ = c.Set(ctx, "string", "hello", 0)
+	_ = c.Set(ctx, "float", 1.5, 0)
+
+	var i int
+	var b bool
+	var s string
+	var f float64
+	for key, dest := range map[string]interface{}{"int": &i, "bool": &b, "string": &s, "float": &f} {
+		if err := c.Get(ctx, key, dest); err != nil {
+			t.Errorf("get %s: %v", key, err)
+		}
+	}
+	if i != 42 || !b || s != "hello" || f != 1.5 {
+		t.Errorf("unexpected values %v %v %v %v", i, b, s, f)
+	}
+	if err := c.Get(ctx, "string", &i); err == nil || c.Stats().Errors != 1 {
+		t.Errorf("expect a decode error, got %v", err)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	ctx := context.Background()
+	t.Run("gob", func(t *testing.T) {
+		c := NewMemoryCache(MemoryCacheConfig{Codec: GobCodec})
+		defer c.Close()
+		_ = c.Set(ctx, "k", testValue{"x"}, 0)
+		var v testValue
+		if err := c.Get(ctx, "k", &v); err != nil || v.V != "x" {
+			t.Errorf("unexpected %v %v", v, err)
+		}
+	})
+	t.Run("proto", func(t *testing.T) {
+		c := NewMemoryCache(MemoryCacheConfig{Codec: ProtoCodec})
+		defer c.Close()
+		if err := c.Set(ctx, "k", wrapperspb.String("x"), 0); err != nil {
+			t.Fatal(err)
+		}
+		v := &wrapperspb.StringValue{}
+		if err := c.Get(ctx, "k", v); err != nil || !proto.Equal(v, wrapperspb.String("x")) {
+			t.Errorf("unexpected %v %v", v, err)
+		}
+		if err := c.Set(ctx, "k", testValue{"x"}, 0); err == nil {
+			t.Error("expect an error for non proto message")
+		}
+	})
+}
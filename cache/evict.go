@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/heap"
+	"sync/atomic"
+)
+
+// EvictReason 条目被移除的原因
+type EvictReason int
+
+const (
+	// EvictExpired 过期
+	EvictExpired EvictReason = iota + 1
+	// EvictCapacity 超过容量被淘汰策略淘汰
+	EvictCapacity
+	// EvictDeleted 被Delete、DeleteMany、DeletePrefix、InvalidateTags或Clear删除
+	EvictDeleted
+	// EvictReplaced 被Set写入的新值替换
+	EvictReplaced
+)
+
+// String
+func (r EvictReason) String() string {
+	switch r {
+	case EvictExpired:
+		return "expired"
+	case EvictCapacity:
+		return "capacity"
+	case EvictDeleted:
+		return "deleted"
+	case EvictReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// evictFunc 淘汰回调
+type evictFunc[K comparable, V any] func(key K, value V, reason EvictReason)
+
+// evicted 持有分片的锁时记录的淘汰，释放锁后回调
+type evicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// listener 分片共用的淘汰回调
+type listener[K comparable, V any] struct {
+	fn atomic.Pointer[evictFunc[K, V]]
+}
+
+// expiryHeap 按过期时间排序的最小堆，只包含设置了过期时间的条目，
+// 清理时只需要弹出堆顶已过期的条目，不需要扫描整个分片
+type expiryHeap[K comparable, V any] []*entry[K, V]
+
+func (h expiryHeap[K, V]) Len() int { return len(h) }
+
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].expireAt < h[j].expireAt }
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+// schedule 按条目的过期时间更新它在堆中的位置
+func (h *expiryHeap[K, V]) schedule(e *entry[K, V]) {
+	switch {
+	case e.expireAt == 0:
+		h.unschedule(e)
+	case e.index < 0:
+		heap.Push(h, e)
+	default:
+		heap.Fix(h, e.index)
+	}
+}
+
+// unschedule 从堆中移除条目
+func (h *expiryHeap[K, V]) unschedule(e *entry[K, V]) {
+	if e.index >= 0 {
+		heap.Remove(h, e.index)
+	}
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// cacheState the response of Handler
+type cacheState struct {
+	Name  string     `json:"name"`
+	Stats CacheStats `json:"stats"`
+	Keys  []KeyInfo  `json:"keys,omitempty"`
+}
+
+// Handler the admin handler of the registered caches:
+//
+//	GET                              the stats of all the caches
+//	GET ?name=users&prefix=user:     the stats and the keys with the prefix, at most limit=100 keys
+//	DELETE ?name=users&prefix=user:  delete the keys by prefix, or by key=, or by tag=
+//	DELETE ?name=users&all=true      clear the cache, an empty prefix is rejected
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		name := q.Get("name")
+		if name == "" {
+			if r.Method != http.MethodGet {
+				writeCacheError(w, http.StatusBadRequest, fmt.Errorf("name is required"))
+				return
+			}
+			states := []cacheState{}
+			caches.Range(func(key, value interface{}) bool {
+				states = append(states, cacheState{Name: key.(string), Stats: value.(Cache).Stats()})
+				return true
+			})
+			sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+			writeCacheJSON(w, states)
+			return
+		}
+
+		c, ok := Registered(name)
+		if !ok {
+			writeCacheError(w, http.StatusNotFound, fmt.Errorf("cache %q not found", name))
+			return
+		}
+		ctx := r.Context()
+		state := cacheState{Name: name}
+		switch r.Method {
+		case http.MethodGet:
+			if q.Has("prefix") {
+				inspector, ok := c.(Inspector)
+				if !ok {
+					writeCacheError(w, http.StatusNotImplemented, fmt.Errorf("cache %q does not support listing keys", name))
+					return
+				}
+				limit := 100
+				if s := q.Get("limit"); s != "" {
+					n, err := strconv.Atoi(s)
+					if err != nil || n <= 0 {
+						writeCacheError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", s))
+						return
+					}
+					limit = n
+				}
+				keys, err := inspector.Keys(ctx, q.Get("prefix"), limit)
+				if err != nil {
+					writeCacheError(w, http.StatusInternalServerError, err)
+					return
+				}
+				state.Keys = keys
+			}
+		case http.MethodDelete:
+			var err error
+			switch {
+			case q.Has("key"):
+				err = c.Delete(ctx, q.Get("key"))
+			case q.Get("prefix") != "":
+				err = c.DeletePrefix(ctx, q.Get("prefix"))
+			case q.Get("all") == "true":
+				err = c.Clear(ctx)
+			case q.Has("tag"):
+				err = c.InvalidateTags(ctx, q["tag"]...)
+			default:
+				writeCacheError(w, http.StatusBadRequest, fmt.Errorf("key, a non-empty prefix, tag or all=true is required"))
+				return
+			}
+			if err != nil {
+				writeCacheError(w, http.StatusInternalServerError, err)
+				return
+			}
+		default:
+			writeCacheError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		state.Stats = c.Stats()
+		writeCacheJSON(w, state)
+	})
+}
+
+// writeCacheJSON
+func writeCacheJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeCacheError
+func writeCacheError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
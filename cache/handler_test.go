@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+	Register("handler", c)
+	defer Unregister("handler")
+
+	_ = c.Set(ctx, "user:1", 1, time.Hour, WithTags("users"))
+	_ = c.Set(ctx, "user:2", 2, 0, WithTags("users"))
+	_ = c.Set(ctx, "team:1", 1, 0)
+
+	do := func(method, target string) (*httptest.ResponseRecorder, cacheState) {
+		w := httptest.NewRecorder()
+		Handler().ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		var state cacheState
+		_ = json.Unmarshal(w.Body.Bytes(), &state)
+		return w, state
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cache", nil))
+	var states []cacheState
+	if err := json.Unmarshal(w.Body.Bytes(), &states); err != nil || len(states) == 0 {
+		t.Fatalf("unexpected list %s", w.Body)
+	}
+
+	_, state := do(http.MethodGet, "/cache?name=handler&prefix=user:&limit=10")
+	if len(state.Keys) != 2 || state.Keys[0].Key != "user:1" || state.Keys[0].TTL == "" ||
+		state.Keys[0].Size != entrySize("user:1", []byte("1"), []string{"users"}) || state.Stats.TotalKeys != 3 {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	if _, state = do(http.MethodDelete, "/cache?name=handler&tag=users"); state.Stats.TotalKeys != 1 {
+		t.Errorf("expect the tag to be invalidated, got %+v", state)
+	}
+	if _, state = do(http.MethodDelete, "/cache?name=handler&prefix=team:"); state.Stats.TotalKeys != 0 {
+		t.Errorf("expect the prefix to be deleted, got %+v", state)
+	}
+
+	for target, code := range map[string]int{
+		"/cache?name=missing":                http.StatusNotFound,
+		"/cache?name=handler&limit=x&prefix": http.StatusBadRequest,
+	} {
+		if w, _ := do(http.MethodGet, target); w.Code != code {
+			t.Errorf("%s: expect %d, got %d", target, code, w.Code)
+		}
+	}
+	if w, _ := do(http.MethodDelete, "/cache?name=handler"); w.Code != http.StatusBadRequest {
+		t.Errorf("expect bad request without key, prefix or tag, got %d", w.Code)
+	}
+
+	_ = c.Set(ctx, "user:1", 1, 0)
+	if w, state := do(http.MethodDelete, "/cache?name=handler&prefix="); w.Code != http.StatusBadRequest || c.Stats().TotalKeys != 1 {
+		t.Errorf("expect an empty prefix to be rejected, got %d %+v", w.Code, state)
+	}
+	if _, state = do(http.MethodDelete, "/cache?name=handler&all=true"); state.Stats.TotalKeys != 0 {
+		t.Errorf("expect the cache to be cleared, got %+v", state)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+	Register("collector", c)
+	defer Unregister("collector")
+
+	_ = c.Set(ctx, "a", 1, 0)
+	_ = c.Get(ctx, "a", new(int))
+	_ = c.Get(ctx, "b", new(int))
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(cacheCollector{})
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["name"] != "collector" {
+				continue
+			}
+			key := f.GetName()
+			if r := labels["result"]; r != "" {
+				key += "/" + r
+			}
+			values[key] = m.GetCounter().GetValue() + m.GetGauge().GetValue()
+		}
+	}
+	for key, want := range map[string]float64{
+		"ginny_cache_requests_total/hit":  1,
+		"ginny_cache_requests_total/miss": 1,
+		"ginny_cache_sets_total":          1,
+		"ginny_cache_keys":                1,
+		"ginny_cache_size_bytes":          float64(entrySize("a", []byte("1"), nil)),
+	} {
+		if values[key] != want {
+			t.Errorf("%s = %v, want %v", key, values[key], want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// LoadFunc 缓存未命中时加载值，返回ErrKeyNotFound（或WithNotFound判断为不存在的错误）表示值不存在
+type LoadFunc[V any] func(ctx context.Context) (V, error)
+
+// Loader 基于Cache的cache-aside加载器：
+//   - 同一个key的并发加载合并为一次（singleflight）
+//   - 不存在的值按WithNegativeTTL缓存，避免穿透
+//   - 过期后的WithStaleWhileRevalidate窗口内返回旧值并在后台刷新
+//   - 加载失败时，过期后的WithStaleIfError窗口内返回旧值
+//   - TTL按WithTTLJitter随机浮动，避免同时过期
+//
+// 缓存中存储的是包含过期时间的包装，Cache的Codec需要支持任意结构，如JSONCodec、GobCodec。
+type Loader[V any] struct {
+	cache Cache
+	group singleflight.Group
+	opts  loadOptions
+}
+
+// loadOptions
+type loadOptions struct {
+	name                 string
+	negativeTTL          time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	jitter               float64
+	notFound             func(error) bool
+}
+
+// LoadOption the option of Loader
+type LoadOption func(*loadOptions)
+
+// WithLoadName the name of the loader in the metrics, default is "default"
+func WithLoadName(name string) LoadOption {
+	return func(o *loadOptions) {
+		o.name = name
+	}
+}
+
+// WithNegativeTTL cache the not found result for the ttl, disabled by default
+func WithNegativeTTL(ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate serve the stale value for d after it expires, while it is reloaded in background
+func WithStaleWhileRevalidate(d time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.staleWhileRevalidate = d
+	}
+}
+
+// WithStaleIfError serve the stale value for d after it expires, if the loader fails
+func WithStaleIfError(d time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.staleIfError = d
+	}
+}
+
+// WithTTLJitter randomize the ttl by ±f, such as 0.1 for ±10%
+func WithTTLJitter(f float64) LoadOption {
+	return func(o *loadOptions) {
+		o.jitter = f
+	}
+}
+
+// WithNotFound the errors of the loader treated as not found, such as sql.ErrNoRows,
+// besides ErrKeyNotFound
+func WithNotFound(f func(error) bool) LoadOption {
+	return func(o *loadOptions) {
+		o.notFound = f
+	}
+}
+
+// loadEntry 缓存中存储的值
+type loadEntry[V any] struct {
+	Value      V     `json:"v"`
+	NotFound   bool  `json:"nf,omitempty"`
+	FreshUntil int64 `json:"fu,omitempty"` // UnixNano，0表示不过期
+}
+
+// result
+func (e *loadEntry[V]) result() (V, error) {
+	if e.NotFound {
+		var zero V
+		return zero, ErrKeyNotFound
+	}
+	return e.Value, nil
+}
+
+// NewLoader 创建加载器
+func NewLoader[V any](c Cache, opts ...LoadOption) *Loader[V] {
+	o := loadOptions{name: "default"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Loader[V]{cache: c, opts: o}
+}
+
+// GetOrLoad 获取缓存值，未命中或过期时调用loader加载并以ttl缓存，ttl为0时不过期。
+// 值不存在时返回ErrKeyNotFound。
+func (l *Loader[V]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader LoadFunc[V]) (V, error) {
+	var cached loadEntry[V]
+	hit := l.cache.Get(ctx, key, &cached) == nil
+	now := time.Now().UnixNano()
+	if hit {
+		if cached.FreshUntil == 0 || now < cached.FreshUntil {
+			return cached.result()
+		}
+		if !cached.NotFound && now < cached.FreshUntil+int64(l.opts.staleWhileRevalidate) {
+			// 返回旧值，后台刷新
+			staleServed.WithLabelValues(l.opts.name, staleRevalidate).Inc()
+			l.group.DoChan(key, func() (interface{}, error) {
+				return l.load(context.WithoutCancel(ctx), key, ttl, loader)
+			})
+			return cached.result()
+		}
+	}
+
+	leader := false
+	v, err, shared := l.group.Do(key, func() (interface{}, error) {
+		leader = true
+		// 不因某个调用者取消而影响其它等待的调用者
+		return l.load(context.WithoutCancel(ctx), key, ttl, loader)
+	})
+	if shared && !leader {
+		loadCoalesced.WithLabelValues(l.opts.name).Inc()
+	}
+	if err != nil {
+		if err != ErrKeyNotFound && hit && !cached.NotFound &&
+			now < cached.FreshUntil+int64(l.opts.staleIfError) {
+			staleServed.WithLabelValues(l.opts.name, staleError).Inc()
+			return cached.result()
+		}
+		var zero V
+		return zero, err
+	}
+
+	value, _ := v.(V)
+	return value, nil
+}
+
+// load 调用loader并写入缓存
+func (l *Loader[V]) load(ctx context.Context, key string, ttl time.Duration, loader LoadFunc[V]) (interface{}, error) {
+	start := time.Now()
+	v, err := loader(ctx)
+	loadDuration.WithLabelValues(l.opts.name).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err == nil:
+		loadsTotal.WithLabelValues(l.opts.name, resultSuccess).Inc()
+		e := loadEntry[V]{Value: v}
+		var expire time.Duration
+		if ttl > 0 {
+			ttl = l.jitter(ttl)
+			e.FreshUntil = start.Add(ttl).UnixNano()
+			expire = ttl + max(l.opts.staleWhileRevalidate, l.opts.staleIfError)
+		}
+		_ = l.cache.Set(ctx, key, e, expire)
+		return v, nil
+	case errors.Is(err, ErrKeyNotFound) || (l.opts.notFound != nil && l.opts.notFound(err)):
+		loadsTotal.WithLabelValues(l.opts.name, resultNotFound).Inc()
+		if l.opts.negativeTTL > 0 {
+			ttl := l.jitter(l.opts.negativeTTL)
+			e := loadEntry[V]{NotFound: true, FreshUntil: start.Add(ttl).UnixNano()}
+			_ = l.cache.Set(ctx, key, e, ttl)
+		}
+		return nil, ErrKeyNotFound
+	default:
+		loadsTotal.WithLabelValues(l.opts.name, resultError).Inc()
+		return nil, err
+	}
+}
+
+// jitter
+func (l *Loader[V]) jitter(ttl time.Duration) time.Duration {
+	if l.opts.jitter <= 0 {
+		return ttl
+	}
+	d := time.Duration(float64(ttl) * l.opts.jitter * (rand.Float64()*2 - 1))
+	if ttl+d <= 0 {
+		return ttl
+	}
+	return ttl + d
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLoaderSingleflight(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+	l := NewLoader[testValue](c, WithLoadName("singleflight"))
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (testValue, error) {
+		calls.Add(1)
+		<-release
+		return testValue{"x"}, nil
+	}
+
+	coalesced := testutil.ToFloat64(loadCoalesced.WithLabelValues("singleflight"))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := l.GetOrLoad(ctx, "k", time.Minute, loader); err != nil || v.V != "x" {
+				t.Errorf("unexpected %v %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expect one load, got %d", calls.Load())
+	}
+	if n := testutil.ToFloat64(loadCoalesced.WithLabelValues("singleflight")) - coalesced; n != 9 {
+		t.Errorf("expect 9 coalesced calls, got %v", n)
+	}
+	// 命中缓存
+	if v, err := l.GetOrLoad(ctx, "k", time.Minute, loader); err != nil || v.V != "x" || calls.Load() != 1 {
+		t.Errorf("expect a cache hit, got %v %v", v, err)
+	}
+}
+
+func TestLoaderNegative(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+	errNoRows := errors.New("no rows")
+	l := NewLoader[int](c, WithNegativeTTL(time.Minute), WithNotFound(func(err error) bool {
+		return errors.Is(err, errNoRows)
+	}))
+
+	var calls int
+	loader := func(ctx context.Context) (int, error) {
+		calls++
+		return 0, errNoRows
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := l.GetOrLoad(ctx, "k", time.Minute, loader); err != ErrKeyNotFound {
+			t.Errorf("expect not found, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expect the not found result to be cached, got %d loads", calls)
+	}
+}
+
+func TestLoaderStale(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+	l := NewLoader[int](c, WithStaleWhileRevalidate(time.Hour))
+
+	var n atomic.Int32
+	loader := func(ctx context.Context) (int, error) {
+		return int(n.Add(1)), nil
+	}
+	if v, _ := l.GetOrLoad(ctx, "k", time.Millisecond, loader); v != 1 {
+		t.Fatalf("expect 1, got %d", v)
+	}
+	time.Sleep(5 * time.Millisecond)
+	// 过期后返回旧值并后台刷新
+	if v, _ := l.GetOrLoad(ctx, "k", time.Hour, loader); v != 1 {
+		t.Errorf("expect the stale value, got %d", v)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, _ := l.GetOrLoad(ctx, "k", time.Hour, loader); v == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expect the value to be refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoaderStaleIfError(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+	l := NewLoader[int](c, WithStaleIfError(time.Hour))
+
+	_, _ = l.GetOrLoad(ctx, "k", time.Millisecond, func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	time.Sleep(5 * time.Millisecond)
+	failed := func(ctx context.Context) (int, error) {
+		return 0, errors.New("unavailable")
+	}
+	if v, err := l.GetOrLoad(ctx, "k", time.Minute, failed); err != nil || v != 1 {
+		t.Errorf("expect the stale value, got %v %v", v, err)
+	}
+	if _, err := l.GetOrLoad(ctx, "other", time.Minute, failed); err == nil {
+		t.Error("expect the error without a stale value")
+	}
+}
+
+func TestLoaderJitter(t *testing.T) {
+	l := NewLoader[int](nil, WithTTLJitter(0.1))
+	for i := 0; i < 100; i++ {
+		if d := l.jitter(time.Minute); d < 54*time.Second || d > 66*time.Second {
+			t.Fatalf("jitter out of range: %v", d)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// MemoryCache 内存缓存实现，值按Codec编码后存储，按key的哈希分片，每个分片有独立的锁和淘汰策略。
+// 不需要编码的场景可以使用Typed直接存储值。
+type MemoryCache struct {
+	store   *store[string, []byte]
+	codec   Codec
+	maxSize int64 // 最大内存使用量（字节）
+
+	// 快照相关
+	snapshotPath string
+	snapshotMu   sync.Mutex
+	stopSnapshot chan struct{}
+}
+
+// MemoryCacheConfig 内存缓存配置
+type MemoryCacheConfig struct {
+	MaxSize         int64          // 最大内存使用量（字节），平均分配到各分片
+	CleanupInterval time.Duration  // 清理过期条目的间隔，默认1秒
+	Shards          int            // 分片数，默认16，向上取整为2的幂
+	Policy          EvictionPolicy // 淘汰策略，默认PolicyLRU
+	Codec           Codec          // 值的编码，默认JSONCodec
+
+	// SnapshotPath 快照文件，设置后启动时加载快照，Close时写入快照
+	SnapshotPath string
+	// SnapshotInterval 定期写入快照的间隔，默认只在Close时写入
+	SnapshotInterval time.Duration
+}
+
+// NewMemoryCache 创建内存缓存
+func NewMemoryCache(config MemoryCacheConfig) *MemoryCache {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 100 * 1024 * 1024 // 默认100MB
+	}
+	if config.Codec == nil {
+		config.Codec = JSONCodec
+	}
+
+	seed := maphash.MakeSeed()
+	c := &MemoryCache{
+		store: newStore[string, []byte](storeConfig{
+			maxSize:         config.MaxSize,
+			entrySize:       1024, // 按平均1KB估算条目数
+			cleanupInterval: config.CleanupInterval,
+			shards:          config.Shards,
+			policy:          config.Policy,
+		}, func(key string) uint64 {
+			return maphash.String(seed, key)
+		}),
+		codec:        config.Codec,
+		maxSize:      config.MaxSize,
+		snapshotPath: config.SnapshotPath,
+		stopSnapshot: make(chan struct{}),
+	}
+
+	if c.snapshotPath != "" {
+		// 快照损坏或不匹配时从空缓存启动
+		if err := c.loadSnapshot(); err != nil {
+			c.store.errors.Add(1)
+		}
+		if config.SnapshotInterval > 0 {
+			go c.snapshotter(config.SnapshotInterval)
+		}
+	}
+
+	return c
+}
+
+const (
+	// entryOverhead 条目结构和map中key、指针的大小
+	entryOverhead = int64(unsafe.Sizeof(entry[string, []byte]{})) + 48
+	// tagOverhead 每个标签的字符串头和标签索引中的大小
+	tagOverhead = 16 + 48
+)
+
+// entrySize 条目占用的内存：key、编码后的值、标签的内容及固定开销
+func entrySize(key string, data []byte, tags []string) int64 {
+	size := entryOverhead + int64(len(key)+len(data))
+	for _, tag := range tags {
+		size += tagOverhead + int64(len(tag))
+	}
+	return size
+}
+
+// Get 获取缓存值，解码到dest
+func (c *MemoryCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.store.get(key)
+	if err != nil {
+		return err
+	}
+
+	if err := c.codec.Unmarshal(data, dest); err != nil {
+		c.store.errors.Add(1)
+		return err
+	}
+
+	return nil
+}
+
+// Set 设置缓存值
+func (c *MemoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) error {
+	if c.store.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		c.store.errors.Add(1)
+		return err
+	}
+
+	tags := newSetOptions(opts).tags
+	return c.store.set(key, data, entrySize(key, data, tags), ttl, tags)
+}
+
+// GetMany 批量获取，每个分片只加锁一次
+func (c *MemoryCache) GetMany(ctx context.Context, keys []string) (Values, error) {
+	values := newValues(len(keys))
+	err := c.store.getMany(keys, func(key string, data []byte) {
+		values.add(key, data, c.codec)
+	})
+	return values, err
+}
+
+// SetMany 批量设置，每个分片只加锁一次
+func (c *MemoryCache) SetMany(ctx context.Context, items map[string]interface{}, ttl time.Duration, opts ...SetOption) error {
+	if c.store.closed.Load() {
+		return ErrCacheClosed
+	}
+
+	tags := newSetOptions(opts).tags
+	encoded := make([]item[string, []byte], 0, len(items))
+	for key, value := range items {
+		data, err := c.codec.Marshal(value)
+		if err != nil {
+			c.store.errors.Add(1)
+			return err
+		}
+		encoded = append(encoded, item[string, []byte]{key: key, value: data, size: entrySize(key, data, tags)})
+	}
+
+	return c.store.setMany(encoded, ttl, tags)
+}
+
+// setEncoded 写入已按codec编码的值，codec不同或ttl返回false时忽略
+func (c *MemoryCache) setEncoded(values Values, ttl func(key string) (time.Duration, bool)) {
+	for key, v := range values.items {
+		if v.codec != c.codec {
+			continue
+		}
+		if d, ok := ttl(key); ok {
+			_ = c.store.set(key, v.data, entrySize(key, v.data, nil), d, nil)
+		}
+	}
+}
+
+// TTLs 返回存在的key的剩余TTL，不过期的key为0
+func (c *MemoryCache) TTLs(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	if c.store.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	ttls := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		if d, ok := c.store.ttl(key); ok {
+			ttls[key] = d
+		}
+	}
+	return ttls, nil
+}
+
+// DeleteMany 批量删除
+func (c *MemoryCache) DeleteMany(ctx context.Context, keys []string) error {
+	return c.store.deleteMany(keys)
+}
+
+// DeletePrefix 删除以prefix开头的key
+func (c *MemoryCache) DeletePrefix(ctx context.Context, prefix string) error {
+	return c.store.deleteFunc(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// OnEvict 设置条目被移除时的回调，value为按Codec编码的值，nil表示取消。
+// 回调在释放分片的锁后同步调用，可以再访问缓存，但耗时的回调会阻塞触发移除的操作。
+func (c *MemoryCache) OnEvict(fn func(key string, value []byte, reason EvictReason)) {
+	c.store.onEvict(fn)
+}
+
+// InvalidateTags 删除带有任一标签的key
+func (c *MemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.store.invalidateTags(tags)
+}
+
+// Delete 删除缓存值
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	return c.store.delete(key)
+}
+
+// Keys 列出以prefix开头的key中排序最前的limit个，limit不大于0时不限制
+func (c *MemoryCache) Keys(ctx context.Context, prefix string, limit int) ([]KeyInfo, error) {
+	var keys []KeyInfo
+	now := time.Now()
+	err := c.store.scan(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}, func(e *entry[string, []byte]) bool {
+		info := KeyInfo{Key: e.key, Size: e.size, Tags: e.tags}
+		if e.expireAt > 0 {
+			info.TTL = time.Unix(0, e.expireAt).Sub(now).Round(time.Millisecond).String()
+		}
+		keys = append(keys, info)
+		return true
+	})
+	// 遍历分片的顺序是随机的，需要排序全部匹配的key后再截取
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, err
+}
+
+// Exists 检查key是否存在，不影响淘汰顺序
+func (c *MemoryCache) Exists(ctx context.Context, key string) bool {
+	return c.store.exists(key)
+}
+
+// Clear 清空缓存
+func (c *MemoryCache) Clear(ctx context.Context) error {
+	return c.store.clear()
+}
+
+// Stats 获取统计信息
+func (c *MemoryCache) Stats() CacheStats {
+	return c.store.stats()
+}
+
+// Close 关闭缓存，配置了快照时先写入快照
+func (c *MemoryCache) Close() error {
+	if c.snapshotPath == "" {
+		return c.store.close()
+	}
+
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+	if c.store.closed.Load() {
+		return nil
+	}
+	close(c.stopSnapshot)
+	err := c.writeSnapshot()
+	if cerr := c.store.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,348 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testValue struct {
+	V string
+}
+
+// testEntrySize the size of a single letter key with testValue{"x"}
+var testEntrySize = entrySize("a", []byte(`{"V":"x"}`), nil)
+
+// newTestCache one shard with room for n values of testValue{"x"}
+func newTestCache(policy EvictionPolicy, n int64) *MemoryCache {
+	return NewMemoryCache(MemoryCacheConfig{MaxSize: n * testEntrySize, Shards: 1, Policy: policy})
+}
+
+// checkAccounting the size of the shards equals to the sum of the entries,
+// and the expiry heap holds exactly the entries with a TTL
+func checkAccounting[K comparable, V any](t *testing.T, s *store[K, V]) {
+	t.Helper()
+	for i, sh := range s.shards {
+		sh.mu.Lock()
+		var bytes int64
+		var expiring int
+		for _, e := range sh.items {
+			bytes += e.size
+			if e.expireAt > 0 {
+				expiring++
+				if e.index < 0 || e.index >= len(sh.expiry) || sh.expiry[e.index] != e {
+					t.Errorf("shard %d: %v is not in the expiry heap", i, e.key)
+				}
+			} else if e.index >= 0 {
+				t.Errorf("shard %d: %v without TTL is in the expiry heap", i, e.key)
+			}
+		}
+		if bytes != sh.bytes || bytes > sh.maxBytes {
+			t.Errorf("shard %d: %d bytes, want %d, max %d", i, sh.bytes, bytes, sh.maxBytes)
+		}
+		if expiring != len(sh.expiry) {
+			t.Errorf("shard %d: %d entries in the expiry heap, want %d", i, len(sh.expiry), expiring)
+		}
+		for j := 1; j < len(sh.expiry); j++ {
+			if sh.expiry[j].expireAt < sh.expiry[(j-1)/2].expireAt {
+				t.Errorf("shard %d: expiry heap out of order at %d", i, j)
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+func TestMemoryCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(PolicyLRU, 3)
+	defer c.Close()
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.Set(ctx, k, testValue{"x"}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var v testValue
+	if err := c.Get(ctx, "a", &v); err != nil || v.V != "x" {
+		t.Fatalf("get a: %v %v", v, err)
+	}
+	_ = c.Set(ctx, "d", testValue{"x"}, 0)
+
+	for k, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if got := c.Exists(ctx, k); got != want {
+			t.Errorf("exists %s = %v, want %v", k, got, want)
+		}
+	}
+	if s := c.Stats(); s.Evictions != 1 || s.TotalKeys != 3 || s.MemoryUsage != 3*testEntrySize {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestMemoryCacheLFU(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(PolicyLFU, 3)
+	defer c.Close()
+
+	var v testValue
+	for _, k := range []string{"a", "b", "c"} {
+		_ = c.Set(ctx, k, testValue{"x"}, 0)
+	}
+	for i := 0; i < 3; i++ {
+		_ = c.Get(ctx, "a", &v)
+	}
+	_ = c.Get(ctx, "c", &v)
+	_ = c.Set(ctx, "d", testValue{"x"}, 0)
+	if c.Exists(ctx, "b") || !c.Exists(ctx, "a") || !c.Exists(ctx, "c") {
+		t.Error("expect the least frequently used b to be evicted")
+	}
+	// d has the lowest frequency now
+	_ = c.Set(ctx, "e", testValue{"x"}, 0)
+	if c.Exists(ctx, "d") || !c.Exists(ctx, "e") {
+		t.Error("expect d to be evicted")
+	}
+}
+
+func TestMemoryCacheTinyLFU(t *testing.T) {
+	ctx := context.Background()
+	// 按最长的key估算，可以容纳约100个条目
+	c := NewMemoryCache(MemoryCacheConfig{
+		MaxSize: 100 * entrySize("scan999", []byte(`{"V":"x"}`), nil), Shards: 1, Policy: PolicyTinyLFU,
+	})
+	defer c.Close()
+
+	var v testValue
+	hot := make([]string, 50)
+	for i := range hot {
+		hot[i] = fmt.Sprintf("hot%d", i)
+		_ = c.Set(ctx, hot[i], testValue{"x"}, 0)
+	}
+	for round := 0; round < 5; round++ {
+		for _, k := range hot {
+			_ = c.Get(ctx, k, &v)
+		}
+	}
+	// a scan of one-hit keys should not flush the hot keys
+	for i := 0; i < 1000; i++ {
+		_ = c.Set(ctx, fmt.Sprintf("scan%d", i), testValue{"x"}, 0)
+	}
+	kept := 0
+	for _, k := range hot {
+		if c.Exists(ctx, k) {
+			kept++
+		}
+	}
+	if kept < 45 {
+		t.Errorf("expect the hot keys to be kept, got %d/%d", kept, len(hot))
+	}
+	checkAccounting(t, c.store)
+}
+
+func TestMemoryCacheExpire(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{})
+	defer c.Close()
+
+	_ = c.Set(ctx, "a", testValue{"x"}, time.Millisecond)
+	_ = c.Set(ctx, "b", testValue{"x"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	var v testValue
+	if err := c.Get(ctx, "a", &v); err != ErrKeyNotFound {
+		t.Errorf("expect expired, got %v", err)
+	}
+	c.store.cleanupExpired()
+	if s := c.Stats(); s.Expirations != 2 || s.TotalKeys != 0 || s.MemoryUsage != 0 || s.Misses != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestMemoryCacheValueTooLarge(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(PolicyLRU, 20)
+	defer c.Close()
+
+	for i := 0; i < 20; i++ {
+		_ = c.Set(ctx, string(rune('a'+i)), testValue{"x"}, 0)
+	}
+	large := testValue{string(make([]byte, 100*1024))}
+	if err := c.Set(ctx, "large", large, 0); err != ErrValueTooLarge {
+		t.Errorf("expect ErrValueTooLarge, got %v", err)
+	}
+	if err := c.SetMany(ctx, map[string]interface{}{"large": large, "x": testValue{"x"}}, 0); err != ErrValueTooLarge {
+		t.Errorf("SetMany: expect ErrValueTooLarge, got %v", err)
+	}
+	if s := c.Stats(); s.Evictions != 1 || s.TotalKeys != 20 || s.Errors != 2 || c.Exists(ctx, "large") {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	checkAccounting(t, c.store)
+}
+
+func TestMemoryCacheKeys(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{Shards: 8})
+	defer c.Close()
+
+	for i := 99; i >= 0; i-- {
+		_ = c.Set(ctx, fmt.Sprintf("k%02d", i), testValue{"x"}, 0)
+	}
+	keys, err := c.Keys(ctx, "k", 5)
+	if err != nil || len(keys) != 5 {
+		t.Fatalf("unexpected keys %v %v", keys, err)
+	}
+	for i, info := range keys {
+		if want := fmt.Sprintf("k%02d", i); info.Key != want {
+			t.Errorf("key %d = %s, want %s", i, info.Key, want)
+		}
+	}
+}
+
+func TestMemoryCacheOnEvict(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(PolicyLRU, 2)
+	defer c.Close()
+
+	var mu sync.Mutex
+	var events []string
+	c.OnEvict(func(key string, value []byte, reason EvictReason) {
+		var v testValue
+		_ = c.codec.Unmarshal(value, &v)
+		// the callback runs without the shard lock
+		_ = c.Exists(ctx, key)
+		mu.Lock()
+		events = append(events, key+"="+v.V+":"+reason.String())
+		mu.Unlock()
+	})
+
+	_ = c.Set(ctx, "a", testValue{"x"}, 0)
+	_ = c.Set(ctx, "a", testValue{"y"}, 0)
+	_ = c.Set(ctx, "b", testValue{"x"}, time.Millisecond)
+	_ = c.Set(ctx, "c", testValue{"x"}, 0)
+	time.Sleep(5 * time.Millisecond)
+	c.store.cleanupExpired()
+	_ = c.Set(ctx, "d", testValue{"x"}, 0)
+	_ = c.Set(ctx, "e", testValue{"x"}, 0)
+	_ = c.Delete(ctx, "d")
+	_ = c.Clear(ctx)
+
+	want := []string{"a=x:replaced", "a=y:capacity", "b=x:expired", "c=x:capacity", "d=x:deleted", "e=x:deleted"}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events %v, want %v", events, want)
+	}
+
+	c.OnEvict(nil)
+	_ = c.Set(ctx, "f", testValue{"x"}, 0)
+	_ = c.Delete(ctx, "f")
+	if len(events) != len(want) {
+		t.Errorf("callback called after removed: %v", events)
+	}
+}
+
+func TestMemoryCacheExpiryHeap(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(MemoryCacheConfig{Shards: 1, CleanupInterval: time.Hour})
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = c.Set(ctx, fmt.Sprintf("k%d", i), testValue{"x"}, time.Duration(100-i)*time.Hour)
+	}
+	// a short TTL, a TTL removed by an update and a deleted key
+	_ = c.Set(ctx, "k50", testValue{"x"}, time.Millisecond)
+	_ = c.Set(ctx, "k60", testValue{"x"}, 0)
+	_ = c.Delete(ctx, "k70")
+	checkAccounting(t, c.store)
+
+	time.Sleep(5 * time.Millisecond)
+	c.store.cleanupExpired()
+	if s := c.Stats(); s.Expirations != 1 || s.TotalKeys != 98 || c.Exists(ctx, "k50") {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	checkAccounting(t, c.store)
+}
+
+func TestMemoryCacheUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	for _, p := range []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyTinyLFU} {
+		c := NewMemoryCache(MemoryCacheConfig{Policy: p})
+		_ = c.Set(ctx, "a", testValue{"x"}, 0)
+		_ = c.Set(ctx, "a", testValue{"xyz"}, 0)
+		if s := c.Stats(); s.TotalKeys != 1 || s.MemoryUsage != entrySize("a", []byte(`{"V":"xyz"}`), nil) {
+			t.Errorf("%s: unexpected stats after update %+v", p, s)
+		}
+		_ = c.Delete(ctx, "a")
+		if s := c.Stats(); s.TotalKeys != 0 || s.MemoryUsage != 0 || s.Deletes != 1 {
+			t.Errorf("%s: unexpected stats after delete %+v", p, s)
+		}
+		_ = c.Close()
+		if err := c.Set(ctx, "a", testValue{"x"}, 0); err != ErrCacheClosed {
+			t.Errorf("%s: expect closed, got %v", p, err)
+		}
+	}
+}
+
+func TestMemoryCacheConcurrency(t *testing.T) {
+	ctx := context.Background()
+	for _, p := range []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyTinyLFU} {
+		c := NewMemoryCache(MemoryCacheConfig{MaxSize: 500 * testEntrySize, Shards: 4, Policy: p})
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(g)))
+				var v testValue
+				for i := 0; i < 2000; i++ {
+					k := fmt.Sprintf("k%d", r.Intn(1000))
+					switch r.Intn(4) {
+					case 0:
+						_ = c.Set(ctx, k, testValue{"x"}, time.Duration(r.Intn(2))*time.Millisecond)
+					case 1:
+						_ = c.Delete(ctx, k)
+					default:
+						_ = c.Get(ctx, k, &v)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		checkAccounting(t, c.store)
+		_ = c.Close()
+	}
+}
+
+func BenchmarkMemoryCache(b *testing.B) {
+	ctx := context.Background()
+	for _, p := range []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyTinyLFU} {
+		for _, shards := range []int{1, 16} {
+			b.Run(fmt.Sprintf("%s/shards=%d", p, shards), func(b *testing.B) {
+				c := NewMemoryCache(MemoryCacheConfig{MaxSize: 10000 * testEntrySize, Shards: shards, Policy: p})
+				defer c.Close()
+				keys := make([]string, 20000)
+				for i := range keys {
+					keys[i] = fmt.Sprintf("key%d", i)
+				}
+				for _, k := range keys[:10000] {
+					_ = c.Set(ctx, k, testValue{"x"}, 0)
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					r := rand.New(rand.NewSource(rand.Int63()))
+					var v testValue
+					for pb.Next() {
+						// 90% reads over a skewed key space, 10% writes
+						k := keys[int(r.ExpFloat64()*2000)%len(keys)]
+						if r.Intn(10) == 0 {
+							_ = c.Set(ctx, k, testValue{"x"}, 0)
+						} else {
+							_ = c.Get(ctx, k, &v)
+						}
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	resultSuccess  = "success"
+	resultError    = "error"
+	resultNotFound = "not_found"
+
+	staleRevalidate = "revalidate"
+	staleError      = "error"
+)
+
+var (
+	loadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ginny_cache_loads_total",
+		Help: "Total number of cache loader calls by result.",
+	}, []string{"name", "result"})
+	loadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ginny_cache_load_duration_seconds",
+		Help:    "Duration of cache loader calls.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"name"})
+	loadCoalesced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ginny_cache_load_coalesced_total",
+		Help: "Total number of GetOrLoad calls which waited for the load of another call.",
+	}, []string{"name"})
+	staleServed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ginny_cache_stale_served_total",
+		Help: "Total number of stale values served by reason.",
+	}, []string{"name", "reason"})
+	publishErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ginny_cache_invalidation_publish_errors_total",
+		Help: "Total number of MultiLevel invalidations which failed to be published.",
+	})
+)
+
+// caches the registered caches by name
+var caches sync.Map
+
+var (
+	requestsDesc = prometheus.NewDesc("ginny_cache_requests_total",
+		"Total number of cache reads by result.", []string{"name", "result"}, nil)
+	setsDesc = prometheus.NewDesc("ginny_cache_sets_total",
+		"Total number of cache writes.", []string{"name"}, nil)
+	deletesDesc = prometheus.NewDesc("ginny_cache_deletes_total",
+		"Total number of deleted cache keys.", []string{"name"}, nil)
+	errorsDesc = prometheus.NewDesc("ginny_cache_errors_total",
+		"Total number of cache errors.", []string{"name"}, nil)
+	evictionsDesc = prometheus.NewDesc("ginny_cache_evictions_total",
+		"Total number of cache keys evicted by the capacity.", []string{"name"}, nil)
+	expirationsDesc = prometheus.NewDesc("ginny_cache_expirations_total",
+		"Total number of expired cache keys.", []string{"name"}, nil)
+	sizeDesc = prometheus.NewDesc("ginny_cache_size_bytes",
+		"Memory used by the cache.", []string{"name"}, nil)
+	keysDesc = prometheus.NewDesc("ginny_cache_keys",
+		"Number of keys in the cache.", []string{"name"}, nil)
+)
+
+func init() {
+	prometheus.MustRegister(loadsTotal, loadDuration, loadCoalesced, staleServed, publishErrors, cacheCollector{})
+}
+
+// Register export the stats of the cache by name, and manage it by the admin endpoint /cache.
+// The cache registered with the same name is replaced.
+func Register(name string, c Cache) {
+	caches.Store(name, c)
+}
+
+// Unregister remove the cache registered by name
+func Unregister(name string) {
+	caches.Delete(name)
+}
+
+// Registered return the cache registered by name
+func Registered(name string) (Cache, bool) {
+	c, ok := caches.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return c.(Cache), true
+}
+
+// cacheCollector export the stats of the registered caches
+type cacheCollector struct{}
+
+// Describe implement prometheus.Collector
+func (cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- setsDesc
+	ch <- deletesDesc
+	ch <- errorsDesc
+	ch <- evictionsDesc
+	ch <- expirationsDesc
+	ch <- sizeDesc
+	ch <- keysDesc
+}
+
+// Collect implement prometheus.Collector
+func (cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	caches.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		s := value.(Cache).Stats()
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(s.Hits), name, "hit")
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(s.Misses), name, "miss")
+		ch <- prometheus.MustNewConstMetric(setsDesc, prometheus.CounterValue, float64(s.Sets), name)
+		ch <- prometheus.MustNewConstMetric(deletesDesc, prometheus.CounterValue, float64(s.Deletes), name)
+		ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(s.Errors), name)
+		ch <- prometheus.MustNewConstMetric(evictionsDesc, prometheus.CounterValue, float64(s.Evictions), name)
+		ch <- prometheus.MustNewConstMetric(expirationsDesc, prometheus.CounterValue, float64(s.Expirations), name)
+		ch <- prometheus.MustNewConstMetric(sizeDesc, prometheus.GaugeValue, float64(s.MemoryUsage), name)
+		ch <- prometheus.MustNewConstMetric(keysDesc, prometheus.GaugeValue, float64(s.TotalKeys), name)
+		return true
+	})
+}
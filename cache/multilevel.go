@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/goriller/ginny/logger"
+	"go.uber.org/zap"
+)
+
+// MultiLevel 两级缓存，L1为本地缓存（通常是MemoryCache），L2为远程缓存：
+// 读取时先读L1，未命中时读L2并回填L1，L2实现了TTLReader时回填的TTL不超过L2中的剩余TTL；
+// 写入和删除同时作用于L2和L1，并通过PubSub广播失效消息，其它实例收到后删除各自L1中的key。
+// 广播失败时只记录日志，不返回错误，因为L2和L1已经写入，其它实例的L1最迟在L1TTL后过期。
+// L1中回填的key没有标签，所以按标签失效时清空L1。
+type MultiLevel struct {
+	l1          Cache
+	l2          Cache
+	l1TTL       time.Duration
+	bus         PubSub
+	channel     string
+	id          string
+	unsubscribe func()
+}
+
+// MultiLevelConfig 两级缓存配置
+type MultiLevelConfig struct {
+	L1      Cache         // 本地缓存
+	L2      Cache         // 远程缓存
+	L1TTL   time.Duration // L1的最长TTL，限制其它实例未收到失效消息时读到旧值的时间，默认1分钟
+	Bus     PubSub        // 失效消息的发布订阅，为空时不广播
+	Channel string        // 失效消息的channel，默认 "ginny:cache:invalidate"
+}
+
+// invalidation 失效消息
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys,omitempty"`
+	Prefix []string `json:"prefix,omitempty"`
+	All    bool     `json:"all,omitempty"`
+}
+
+// NewMultiLevel 创建两级缓存，有Bus时订阅失效消息
+func NewMultiLevel(config MultiLevelConfig) (*MultiLevel, error) {
+	if config.L1TTL <= 0 {
+		config.L1TTL = time.Minute
+	}
+	if config.Channel == "" {
+		config.Channel = "ginny:cache:invalidate"
+	}
+
+	c := &MultiLevel{
+		l1:      config.L1,
+		l2:      config.L2,
+		l1TTL:   config.L1TTL,
+		bus:     config.Bus,
+		channel: config.Channel,
+		id:      uuid.NewString(),
+	}
+	if c.bus != nil {
+		unsubscribe, err := c.bus.Subscribe(context.Background(), c.channel, c.onInvalidate)
+		if err != nil {
+			return nil, err
+		}
+		c.unsubscribe = unsubscribe
+	}
+	return c, nil
+}
+
+// onInvalidate 删除其它实例失效的L1 key
+func (c *MultiLevel) onInvalidate(msg []byte) {
+	var inv invalidation
+	if err := json.Unmarshal(msg, &inv); err != nil || inv.Origin == c.id {
+		return
+	}
+	ctx := context.Background()
+	if inv.All {
+		_ = c.l1.Clear(ctx)
+		return
+	}
+	if len(inv.Keys) > 0 {
+		_ = c.l1.DeleteMany(ctx, inv.Keys)
+	}
+	for _, prefix := range inv.Prefix {
+		_ = c.l1.DeletePrefix(ctx, prefix)
+	}
+}
+
+// publish 广播失效消息，失败时记录日志
+func (c *MultiLevel) publish(ctx context.Context, inv invalidation) {
+	if c.bus == nil {
+		return
+	}
+	inv.Origin = c.id
+	msg, err := json.Marshal(inv)
+	if err == nil {
+		err = c.bus.Publish(ctx, c.channel, msg)
+	}
+	if err != nil {
+		publishErrors.Inc()
+		logger.Default().Warn("Cache invalidation publish error.", zap.String("channel", c.channel), zap.Error(err))
+	}
+}
+
+// l1TTLOf L1的TTL不超过L1TTL
+func (c *MultiLevel) l1TTLOf(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > c.l1TTL {
+		return c.l1TTL
+	}
+	return ttl
+}
+
+// backfillTTLs 回填L1的TTL，不超过L1TTL和L2中的剩余TTL，读取剩余TTL失败或key已不存在时不回填
+func (c *MultiLevel) backfillTTLs(ctx context.Context, keys []string) func(key string) (time.Duration, bool) {
+	r, ok := c.l2.(TTLReader)
+	if !ok {
+		return func(string) (time.Duration, bool) { return c.l1TTL, true }
+	}
+	ttls, err := r.TTLs(ctx, keys)
+	return func(key string) (time.Duration, bool) {
+		ttl, ok := ttls[key]
+		if err != nil || !ok {
+			return 0, false
+		}
+		return c.l1TTLOf(ttl), true
+	}
+}
+
+// Get 先读L1，未命中时读L2并回填L1
+func (c *MultiLevel) Get(ctx context.Context, key string, dest interface{}) error {
+	if err := c.l1.Get(ctx, key, dest); err == nil {
+		return nil
+	}
+	if err := c.l2.Get(ctx, key, dest); err != nil {
+		return err
+	}
+	if ttl, ok := c.backfillTTLs(ctx, []string{key})(key); ok {
+		_ = c.l1.Set(ctx, key, dest, ttl)
+	}
+	return nil
+}
+
+// Set 写入L2和L1，并广播失效消息
+func (c *MultiLevel) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) error {
+	if err := c.l2.Set(ctx, key, value, ttl, opts...); err != nil {
+		return err
+	}
+	_ = c.l1.Set(ctx, key, value, c.l1TTLOf(ttl), opts...)
+	c.publish(ctx, invalidation{Keys: []string{key}})
+	return nil
+}
+
+// Delete 删除L2和L1，并广播失效消息
+func (c *MultiLevel) Delete(ctx context.Context, key string) error {
+	if err := c.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+	_ = c.l1.Delete(ctx, key)
+	c.publish(ctx, invalidation{Keys: []string{key}})
+	return nil
+}
+
+// GetMany 先读L1，未命中的key从L2读取，L1是MemoryCache且编码相同时回填L1
+func (c *MultiLevel) GetMany(ctx context.Context, keys []string) (Values, error) {
+	values, err := c.l1.GetMany(ctx, keys)
+	if err != nil {
+		values = newValues(len(keys))
+	}
+	missing := make([]string, 0, len(keys)-values.Len())
+	for _, key := range keys {
+		if !values.Has(key) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	remote, err := c.l2.GetMany(ctx, missing)
+	if err != nil {
+		return values, err
+	}
+	if l1, ok := c.l1.(*MemoryCache); ok && remote.Len() > 0 {
+		found := make([]string, 0, remote.Len())
+		for key := range remote.items {
+			found = append(found, key)
+		}
+		l1.setEncoded(remote, c.backfillTTLs(ctx, found))
+	}
+	for key, v := range remote.items {
+		values.items[key] = v
+	}
+	return values, nil
+}
+
+// SetMany 批量写入L2和L1，并广播失效消息
+func (c *MultiLevel) SetMany(ctx context.Context, items map[string]interface{}, ttl time.Duration, opts ...SetOption) error {
+	if err := c.l2.SetMany(ctx, items, ttl, opts...); err != nil {
+		return err
+	}
+	_ = c.l1.SetMany(ctx, items, c.l1TTLOf(ttl), opts...)
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	c.publish(ctx, invalidation{Keys: keys})
+	return nil
+}
+
+// DeleteMany 批量删除L2和L1，并广播失效消息
+func (c *MultiLevel) DeleteMany(ctx context.Context, keys []string) error {
+	if err := c.l2.DeleteMany(ctx, keys); err != nil {
+		return err
+	}
+	_ = c.l1.DeleteMany(ctx, keys)
+	c.publish(ctx, invalidation{Keys: keys})
+	return nil
+}
+
+// DeletePrefix 删除L2和L1中以prefix开头的key，并广播失效消息
+func (c *MultiLevel) DeletePrefix(ctx context.Context, prefix string) error {
+	if err := c.l2.DeletePrefix(ctx, prefix); err != nil {
+		return err
+	}
+	_ = c.l1.DeletePrefix(ctx, prefix)
+	c.publish(ctx, invalidation{Prefix: []string{prefix}})
+	return nil
+}
+
+// InvalidateTags 删除L2中带有标签的key，清空L1，并广播失效消息
+func (c *MultiLevel) InvalidateTags(ctx context.Context, tags ...string) error {
+	if err := c.l2.InvalidateTags(ctx, tags...); err != nil {
+		return err
+	}
+	_ = c.l1.Clear(ctx)
+	c.publish(ctx, invalidation{All: true})
+	return nil
+}
+
+// Keys 列出L2中以prefix开头的key，L2不支持时列出L1的
+func (c *MultiLevel) Keys(ctx context.Context, prefix string, limit int) ([]KeyInfo, error) {
+	if i, ok := c.l2.(Inspector); ok {
+		return i.Keys(ctx, prefix, limit)
+	}
+	if i, ok := c.l1.(Inspector); ok {
+		return i.Keys(ctx, prefix, limit)
+	}
+	return nil, errors.New("cache: listing keys is not supported")
+}
+
+// Exists 检查L1或L2中key是否存在
+func (c *MultiLevel) Exists(ctx context.Context, key string) bool {
+	return c.l1.Exists(ctx, key) || c.l2.Exists(ctx, key)
+}
+
+// Clear 清空L2和L1，并广播失效消息
+func (c *MultiLevel) Clear(ctx context.Context) error {
+	if err := c.l2.Clear(ctx); err != nil {
+		return err
+	}
+	_ = c.l1.Clear(ctx)
+	c.publish(ctx, invalidation{All: true})
+	return nil
+}
+
+// Stats 获取统计信息，命中数为两级命中之和，未命中、写入和删除数以L2为准，
+// key数、内存、淘汰和过期数以L1为准
+func (c *MultiLevel) Stats() CacheStats {
+	l1, l2 := c.l1.Stats(), c.l2.Stats()
+	stats := CacheStats{
+		Hits:        l1.Hits + l2.Hits,
+		Misses:      l2.Misses,
+		Sets:        l2.Sets,
+		Deletes:     l2.Deletes,
+		Errors:      l1.Errors + l2.Errors,
+		Evictions:   l1.Evictions,
+		Expirations: l1.Expirations,
+		TotalKeys:   l1.TotalKeys,
+		MemoryUsage: l1.MemoryUsage,
+	}
+	total := stats.Hits + stats.Misses
+	if total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// Close 取消订阅并关闭两级缓存
+func (c *MultiLevel) Close() error {
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+	}
+	err := c.l1.Close()
+	if err2 := c.l2.Close(); err2 != nil {
+		err = err2
+	}
+	return err
+}
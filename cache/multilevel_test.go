@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestMultiLevel(t *testing.T, f *fakeRedis, bus PubSub) *MultiLevel {
+	c, err := NewMultiLevel(MultiLevelConfig{
+		L1:  NewMemoryCache(MemoryCacheConfig{}),
+		L2:  NewRedisCache(RedisCacheConfig{Dial: f.dial, Prefix: "app:"}),
+		Bus: bus,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestMultiLevel(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	bus := NewMemoryBus()
+	a, b := newTestMultiLevel(t, f, bus), newTestMultiLevel(t, f, bus)
+
+	_ = a.Set(ctx, "k", testValue{"v1"}, time.Hour)
+	if !a.l1.Exists(ctx, "k") || !a.l2.Exists(ctx, "k") {
+		t.Fatal("expect write through")
+	}
+
+	// b从L2读取后回填L1
+	var v testValue
+	if err := b.Get(ctx, "k", &v); err != nil || v.V != "v1" {
+		t.Fatalf("get: %v %v", v, err)
+	}
+	if !b.l1.Exists(ctx, "k") {
+		t.Error("expect read through to fill L1")
+	}
+	commands := f.commands.Load()
+	if err := b.Get(ctx, "k", &v); err != nil || f.commands.Load() != commands {
+		t.Error("expect a L1 hit")
+	}
+
+	// a更新后b的L1失效
+	_ = a.Set(ctx, "k", testValue{"v2"}, time.Hour)
+	if b.l1.Exists(ctx, "k") {
+		t.Error("expect b's L1 to be invalidated")
+	}
+	if err := b.Get(ctx, "k", &v); err != nil || v.V != "v2" {
+		t.Errorf("expect the new value, got %v %v", v, err)
+	}
+	if !a.l1.Exists(ctx, "k") {
+		t.Error("expect a's own L1 not to be invalidated")
+	}
+
+	_ = a.Delete(ctx, "k")
+	if b.Exists(ctx, "k") || b.Get(ctx, "k", &v) != ErrKeyNotFound {
+		t.Error("expect the key to be deleted on b")
+	}
+
+	_ = b.Set(ctx, "x", 1, 0)
+	_ = b.Get(ctx, "x", new(int))
+	_ = a.Get(ctx, "x", new(int))
+	_ = b.Clear(ctx)
+	if a.l1.Exists(ctx, "x") {
+		t.Error("expect a's L1 to be cleared")
+	}
+	if s := b.Stats(); s.Hits == 0 || s.Sets != 1 || s.Misses == 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestMultiLevelL1TTL(t *testing.T) {
+	ctx := context.Background()
+	c, _ := NewMultiLevel(MultiLevelConfig{
+		L1:    NewMemoryCache(MemoryCacheConfig{}),
+		L2:    NewMemoryCache(MemoryCacheConfig{}),
+		L1TTL: time.Millisecond,
+	})
+	defer c.Close()
+
+	_ = c.Set(ctx, "k", 1, time.Hour)
+	time.Sleep(5 * time.Millisecond)
+	if c.l1.Exists(ctx, "k") || !c.l2.Exists(ctx, "k") {
+		t.Error("expect L1 to expire before L2")
+	}
+}
+
+func TestMultiLevelBackfillTTL(t *testing.T) {
+	ctx := context.Background()
+	c, _ := NewMultiLevel(MultiLevelConfig{
+		L1: NewMemoryCache(MemoryCacheConfig{}),
+		L2: NewMemoryCache(MemoryCacheConfig{}),
+	})
+	defer c.Close()
+
+	// L1中回填的key不比L2中的活得久
+	_ = c.l2.Set(ctx, "a", 1, 20*time.Millisecond)
+	_ = c.l2.Set(ctx, "b", 1, 20*time.Millisecond)
+	_ = c.l2.Set(ctx, "c", 1, 0)
+	if err := c.Get(ctx, "a", new(int)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetMany(ctx, []string{"b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	ttls, _ := c.l1.(TTLReader).TTLs(ctx, []string{"a", "b", "c"})
+	if ttls["a"] <= 0 || ttls["a"] > 20*time.Millisecond || ttls["b"] <= 0 || ttls["b"] > 20*time.Millisecond {
+		t.Errorf("expect the backfill capped by L2, got %v", ttls)
+	}
+	if ttls["c"] <= 20*time.Millisecond || ttls["c"] > time.Minute {
+		t.Errorf("expect L1TTL for the key without expiry, got %v", ttls)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if c.l1.Exists(ctx, "a") || c.l1.Exists(ctx, "b") {
+		t.Error("expect L1 to expire with L2")
+	}
+}
+
+// failingBus 发布总是失败
+type failingBus struct{}
+
+func (failingBus) Publish(ctx context.Context, channel string, msg []byte) error {
+	return errors.New("bus is down")
+}
+
+func (failingBus) Subscribe(ctx context.Context, channel string, fn func(msg []byte)) (func(), error) {
+	return func() {}, nil
+}
+
+func TestMultiLevelPublishError(t *testing.T) {
+	ctx := context.Background()
+	c, _ := NewMultiLevel(MultiLevelConfig{
+		L1:  NewMemoryCache(MemoryCacheConfig{}),
+		L2:  NewMemoryCache(MemoryCacheConfig{}),
+		Bus: failingBus{},
+	})
+	defer c.Close()
+
+	failed := testutil.ToFloat64(publishErrors)
+	if err := c.Set(ctx, "k", 1, 0); err != nil {
+		t.Errorf("the written value should not fail on publish, got %v", err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Errorf("the deleted value should not fail on publish, got %v", err)
+	}
+	if n := testutil.ToFloat64(publishErrors) - failed; n != 2 {
+		t.Errorf("publish errors = %v, want 2", n)
+	}
+}
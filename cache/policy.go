@@ -0,0 +1,350 @@
+package cache
+
+// EvictionPolicy 淘汰策略
+type EvictionPolicy string
+
+const (
+	// PolicyLRU 淘汰最久未访问的（默认）
+	PolicyLRU EvictionPolicy = "lru"
+	// PolicyLFU 淘汰访问次数最少的，次数相同时淘汰最久未访问的
+	PolicyLFU EvictionPolicy = "lfu"
+	// PolicyTinyLFU W-TinyLFU，新条目先进入窗口LRU，再按估算的访问频率与主区的淘汰候选竞争准入
+	PolicyTinyLFU EvictionPolicy = "tinylfu"
+)
+
+// entry 缓存条目，同时是淘汰策略链表的节点
+type entry[K comparable, V any] struct {
+	key      K
+	hash     uint64
+	value    V
+	size     int64
+	expireAt int64 // UnixNano，0表示不过期
+	tags     []string
+	index    int // 在过期堆中的位置，-1表示不在堆中
+
+	prev, next *entry[K, V]
+	list       *entryList[K, V]
+	freq       *freqNode[K, V]
+}
+
+// expired
+func (e *entry[K, V]) expired(now int64) bool {
+	return e.expireAt > 0 && now >= e.expireAt
+}
+
+// entryList 侵入式双向链表，记录条目数和大小
+type entryList[K comparable, V any] struct {
+	root  entry[K, V]
+	len   int
+	bytes int64
+}
+
+// newEntryList
+func newEntryList[K comparable, V any]() *entryList[K, V] {
+	l := &entryList[K, V]{}
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	return l
+}
+
+// pushFront
+func (l *entryList[K, V]) pushFront(e *entry[K, V]) {
+	e.prev = &l.root
+	e.next = l.root.next
+	l.root.next.prev = e
+	l.root.next = e
+	e.list = l
+	l.len++
+	l.bytes += e.size
+}
+
+// remove
+func (l *entryList[K, V]) remove(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next, e.list = nil, nil, nil
+	l.len--
+	l.bytes -= e.size
+}
+
+// moveToFront
+func (l *entryList[K, V]) moveToFront(e *entry[K, V]) {
+	if l.root.next == e {
+		return
+	}
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev = &l.root
+	e.next = l.root.next
+	l.root.next.prev = e
+	l.root.next = e
+}
+
+// back 链表尾部，即最久未访问的
+func (l *entryList[K, V]) back() *entry[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// resize 更新条目大小
+func resize[K comparable, V any](e *entry[K, V], size int64) {
+	if e.list != nil {
+		e.list.bytes += size - e.size
+	}
+	e.size = size
+}
+
+// policy 淘汰策略，由分片的锁保护，所有操作都是O(1)
+type policy[K comparable, V any] interface {
+	// add 新增条目
+	add(e *entry[K, V])
+	// access 条目被访问或更新
+	access(e *entry[K, V])
+	// remove 删除条目
+	remove(e *entry[K, V])
+	// victim 下一个淘汰的条目，为空时返回nil
+	victim() *entry[K, V]
+}
+
+// newPolicy capacity为分片的最大容量，entries为估算的条目数
+func newPolicy[K comparable, V any](p EvictionPolicy, capacity, entries int64) policy[K, V] {
+	switch p {
+	case PolicyLFU:
+		return newLFUPolicy[K, V]()
+	case PolicyTinyLFU:
+		return newTinyLFUPolicy[K, V](capacity, entries)
+	default:
+		return &lruPolicy[K, V]{list: newEntryList[K, V]()}
+	}
+}
+
+// lruPolicy
+type lruPolicy[K comparable, V any] struct {
+	list *entryList[K, V]
+}
+
+func (p *lruPolicy[K, V]) add(e *entry[K, V])    { p.list.pushFront(e) }
+func (p *lruPolicy[K, V]) access(e *entry[K, V]) { p.list.moveToFront(e) }
+func (p *lruPolicy[K, V]) remove(e *entry[K, V]) { p.list.remove(e) }
+func (p *lruPolicy[K, V]) victim() *entry[K, V]  { return p.list.back() }
+
+// freqNode 相同访问次数的条目，按次数升序链接
+type freqNode[K comparable, V any] struct {
+	freq       uint64
+	items      *entryList[K, V]
+	prev, next *freqNode[K, V]
+}
+
+// lfuPolicy O(1) LFU
+type lfuPolicy[K comparable, V any] struct {
+	head freqNode[K, V]
+}
+
+// newLFUPolicy
+func newLFUPolicy[K comparable, V any]() *lfuPolicy[K, V] {
+	p := &lfuPolicy[K, V]{}
+	p.head.next = &p.head
+	p.head.prev = &p.head
+	return p
+}
+
+// insertAfter 在at之后插入次数为freq的节点
+func (p *lfuPolicy[K, V]) insertAfter(at *freqNode[K, V], freq uint64) *freqNode[K, V] {
+	n := &freqNode[K, V]{freq: freq, items: newEntryList[K, V](), prev: at, next: at.next}
+	at.next.prev = n
+	at.next = n
+	return n
+}
+
+// unlinkIfEmpty
+func (p *lfuPolicy[K, V]) unlinkIfEmpty(n *freqNode[K, V]) {
+	if n.items.len > 0 {
+		return
+	}
+	n.prev.next = n.next
+	n.next.prev = n.prev
+}
+
+func (p *lfuPolicy[K, V]) add(e *entry[K, V]) {
+	n := p.head.next
+	if n == &p.head || n.freq != 1 {
+		n = p.insertAfter(&p.head, 1)
+	}
+	n.items.pushFront(e)
+	e.freq = n
+}
+
+func (p *lfuPolicy[K, V]) access(e *entry[K, V]) {
+	cur := e.freq
+	next := cur.next
+	if next == &p.head || next.freq != cur.freq+1 {
+		next = p.insertAfter(cur, cur.freq+1)
+	}
+	cur.items.remove(e)
+	next.items.pushFront(e)
+	e.freq = next
+	p.unlinkIfEmpty(cur)
+}
+
+func (p *lfuPolicy[K, V]) remove(e *entry[K, V]) {
+	n := e.freq
+	n.items.remove(e)
+	e.freq = nil
+	p.unlinkIfEmpty(n)
+}
+
+func (p *lfuPolicy[K, V]) victim() *entry[K, V] {
+	if p.head.next == &p.head {
+		return nil
+	}
+	return p.head.next.items.back()
+}
+
+// tinyLFUPolicy W-TinyLFU：1%的窗口LRU，主区为分段LRU（20%试用区，80%保护区），
+// 窗口溢出的条目与试用区的淘汰候选比较估算频率，频率高的留下。
+type tinyLFUPolicy[K comparable, V any] struct {
+	window       *entryList[K, V]
+	probation    *entryList[K, V]
+	protected    *entryList[K, V]
+	windowMax    int64
+	mainMax      int64
+	protectedMax int64
+	sketch       *cmSketch
+}
+
+// newTinyLFUPolicy
+func newTinyLFUPolicy[K comparable, V any](capacity, entries int64) *tinyLFUPolicy[K, V] {
+	windowMax := capacity / 100
+	if windowMax < 1 {
+		windowMax = 1
+	}
+	return &tinyLFUPolicy[K, V]{
+		window:       newEntryList[K, V](),
+		probation:    newEntryList[K, V](),
+		protected:    newEntryList[K, V](),
+		windowMax:    windowMax,
+		mainMax:      capacity - windowMax,
+		protectedMax: (capacity - windowMax) * 8 / 10,
+		sketch:       newCMSketch(entries),
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) add(e *entry[K, V]) {
+	p.sketch.increment(e.hash)
+	p.window.pushFront(e)
+}
+
+func (p *tinyLFUPolicy[K, V]) access(e *entry[K, V]) {
+	p.sketch.increment(e.hash)
+	switch e.list {
+	case p.window, p.protected:
+		e.list.moveToFront(e)
+	case p.probation:
+		p.probation.remove(e)
+		p.protected.pushFront(e)
+		for p.protected.bytes > p.protectedMax && p.protected.len > 1 {
+			demoted := p.protected.back()
+			p.protected.remove(demoted)
+			p.probation.pushFront(demoted)
+		}
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) remove(e *entry[K, V]) {
+	e.list.remove(e)
+}
+
+func (p *tinyLFUPolicy[K, V]) victim() *entry[K, V] {
+	for p.window.bytes > p.windowMax {
+		candidate := p.window.back()
+		// 主区未满时直接准入
+		if p.probation.bytes+p.protected.bytes+candidate.size <= p.mainMax {
+			p.window.remove(candidate)
+			p.probation.pushFront(candidate)
+			continue
+		}
+		main := p.probation.back()
+		if main == nil {
+			main = p.protected.back()
+		}
+		if main == nil || p.sketch.estimate(candidate.hash) > p.sketch.estimate(main.hash) {
+			// 候选者准入主区，淘汰主区的候选
+			p.window.remove(candidate)
+			p.probation.pushFront(candidate)
+			if main == nil {
+				continue
+			}
+			return main
+		}
+		return candidate
+	}
+	for _, l := range []*entryList[K, V]{p.probation, p.protected, p.window} {
+		if e := l.back(); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// cmSketch 4位计数的Count-Min Sketch，计数总数达到10倍宽度时减半以淘汰旧的频率
+type cmSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// newCMSketch
+func newCMSketch(entries int64) *cmSketch {
+	width := int64(1024)
+	for width < entries && width < 1<<20 {
+		width <<= 1
+	}
+	s := &cmSketch{mask: uint64(width - 1), resetAt: int(width) * 10}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index 第i行的位置
+func (s *cmSketch) index(h uint64, i int) uint64 {
+	h1, h2 := h, h>>32|h<<32
+	return (h1 + uint64(i)*h2) & s.mask
+}
+
+// increment
+func (s *cmSketch) increment(h uint64) {
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < 15 {
+			*c++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+// estimate
+func (s *cmSketch) estimate(h uint64) uint8 {
+	min := uint8(15)
+	for i := range s.rows {
+		if c := s.rows[i][s.index(h, i)]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// reset
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// PubSub 发布订阅，用于在多个实例之间广播缓存失效消息，可以基于Redis、NATS、Kafka等实现
+type PubSub interface {
+	// Publish 发布消息到channel
+	Publish(ctx context.Context, channel string, msg []byte) error
+	// Subscribe 订阅channel，返回取消订阅的函数
+	Subscribe(ctx context.Context, channel string, handler func(msg []byte)) (func(), error)
+}
+
+// MemoryBus 进程内的PubSub，Publish同步调用所有订阅者，用于测试或单进程内的多个缓存
+type MemoryBus struct {
+	mu   sync.RWMutex
+	subs map[string]map[int]func([]byte)
+	next int
+}
+
+// NewMemoryBus 创建进程内的PubSub
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[string]map[int]func([]byte))}
+}
+
+// Publish 发布消息到channel
+func (b *MemoryBus) Publish(ctx context.Context, channel string, msg []byte) error {
+	b.mu.RLock()
+	handlers := make([]func([]byte), 0, len(b.subs[channel]))
+	for _, h := range b.subs[channel] {
+		handlers = append(handlers, h)
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(msg)
+	}
+	return nil
+}
+
+// Subscribe 订阅channel
+func (b *MemoryBus) Subscribe(ctx context.Context, channel string, handler func(msg []byte)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	if b.subs[channel] == nil {
+		b.subs[channel] = make(map[int]func([]byte))
+	}
+	b.subs[channel][id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[channel], id)
+	}, nil
+}
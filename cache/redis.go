@@ -0,0 +1,614 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RedisCache Redis协议的远程缓存，值按Codec编码，key加上Prefix作为命名空间
+type RedisCache struct {
+	pool   *redisPool
+	codec  Codec
+	prefix string
+	flush  bool
+	closed atomic.Bool
+
+	hits    atomic.Int64
+	misses  atomic.Int64
+	sets    atomic.Int64
+	deletes atomic.Int64
+	errors  atomic.Int64
+}
+
+// RedisCacheConfig Redis缓存配置
+type RedisCacheConfig struct {
+	Addr     string        // 地址，默认127.0.0.1:6379
+	Password string        // 密码
+	DB       int           // 数据库
+	Prefix   string        // key的前缀，如 "app:user:"，Clear只删除前缀下的key
+	FlushDB  bool          // 没有Prefix时允许Clear以FLUSHDB清空整个数据库，包括其他应用的key
+	PoolSize int           // 最大连接数，默认10
+	Timeout  time.Duration // 单次请求的超时，默认3秒
+	Codec    Codec         // 值的编码，默认JSONCodec
+	// Dial 连接工厂，默认按Addr拨号TCP
+	Dial func(ctx context.Context) (net.Conn, error)
+}
+
+// NewRedisCache 创建Redis缓存，连接在使用时建立
+func NewRedisCache(config RedisCacheConfig) *RedisCache {
+	if config.Addr == "" {
+		config.Addr = "127.0.0.1:6379"
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = 10
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 3 * time.Second
+	}
+	if config.Codec == nil {
+		config.Codec = JSONCodec
+	}
+	if config.Dial == nil {
+		dialer := &net.Dialer{Timeout: config.Timeout}
+		addr := config.Addr
+		config.Dial = func(ctx context.Context) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		}
+	}
+
+	init := func(c *redisConn, deadline time.Time) error {
+		var cmds [][]interface{}
+		if config.Password != "" {
+			cmds = append(cmds, []interface{}{"AUTH", config.Password})
+		}
+		if config.DB != 0 {
+			cmds = append(cmds, []interface{}{"SELECT", config.DB})
+		}
+		if len(cmds) == 0 {
+			return nil
+		}
+		replies, err := c.pipeline(deadline, cmds...)
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			if err, ok := reply.(RedisError); ok {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return &RedisCache{
+		pool:   newRedisPool(config.PoolSize, config.Timeout, config.Dial, init),
+		codec:  config.Codec,
+		prefix: config.Prefix,
+		flush:  config.FlushDB,
+	}
+}
+
+// key
+func (c *RedisCache) key(key string) string {
+	return c.prefix + key
+}
+
+// do 执行命令，错误回复作为error返回
+func (c *RedisCache) do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	replies, err := c.pool.do(ctx, args)
+	if err != nil {
+		c.errors.Add(1)
+		return nil, err
+	}
+	if err, ok := replies[0].(RedisError); ok {
+		c.errors.Add(1)
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// pipeline 在一个连接上执行多个命令，有错误回复时返回第一个
+func (c *RedisCache) pipeline(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error) {
+	if c.closed.Load() {
+		return nil, ErrCacheClosed
+	}
+	replies, err := c.pool.do(ctx, cmds...)
+	if err != nil {
+		c.errors.Add(1)
+		return nil, err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(RedisError); ok {
+			c.errors.Add(1)
+			return nil, err
+		}
+	}
+	return replies, nil
+}
+
+// setArgs SET命令，ttl大于0时以毫秒设置过期
+func (c *RedisCache) setArgs(key string, data []byte, ttl time.Duration) []interface{} {
+	args := []interface{}{"SET", c.key(key), data}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+		args = append(args, "PX", ms)
+	}
+	return args
+}
+
+// Get 获取缓存值，解码到dest
+func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
+	reply, err := c.do(ctx, "GET", c.key(key))
+	if err != nil {
+		return err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		c.misses.Add(1)
+		return ErrKeyNotFound
+	}
+	c.hits.Add(1)
+
+	if err := c.codec.Unmarshal(data, dest); err != nil {
+		c.errors.Add(1)
+		return err
+	}
+	return nil
+}
+
+// tagKey 标签的集合，成员为带前缀的key
+func (c *RedisCache) tagKey(tag string) string {
+	return c.prefix + "__tag__:" + tag
+}
+
+// tagScript 把key加入标签的集合，集合的过期时间不短于成员中最长的ttl，有不过期的成员时集合不过期。
+// KEYS[1]为集合，ARGV[1]为毫秒的ttl，0表示不过期，其余为成员
+const tagScript = `local ttl = tonumber(ARGV[1])
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('SADD', KEYS[1], unpack(ARGV, 2))
+if ttl == 0 then
+	redis.call('PERSIST', KEYS[1])
+elseif existed == 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+else
+	local current = redis.call('PTTL', KEYS[1])
+	if current >= 0 and current < ttl then
+		redis.call('PEXPIRE', KEYS[1], ttl)
+	end
+end
+return 1`
+
+// tagBatch 每次EVAL的最多成员数，避免超过Lua unpack的限制
+const tagBatch = 1000
+
+// tagArgs 把key加入标签的集合并延长集合的过期时间
+func (c *RedisCache) tagArgs(tags []string, keys []interface{}, ttl time.Duration) [][]interface{} {
+	var ms int64
+	if ttl > 0 {
+		if ms = ttl.Milliseconds(); ms < 1 {
+			ms = 1
+		}
+	}
+	var cmds [][]interface{}
+	for _, tag := range tags {
+		for i := 0; i < len(keys); i += tagBatch {
+			batch := keys[i:min(i+tagBatch, len(keys))]
+			args := make([]interface{}, 0, len(batch)+5)
+			args = append(args, "EVAL", tagScript, 1, c.tagKey(tag), ms)
+			args = append(args, batch...)
+			cmds = append(cmds, args)
+		}
+	}
+	return cmds
+}
+
+// pruneTags 删除标签的集合中已经不存在的key，这些key被删除或过期时没有从集合中移除
+func (c *RedisCache) pruneTags(ctx context.Context, sets []string) error {
+	for _, set := range sets {
+		reply, err := c.do(ctx, "SMEMBERS", set)
+		if err != nil {
+			return err
+		}
+		members, _ := reply.([]interface{})
+		if len(members) == 0 {
+			continue
+		}
+		cmds := make([][]interface{}, 0, len(members))
+		for _, member := range members {
+			cmds = append(cmds, []interface{}{"EXISTS", member})
+		}
+		replies, err := c.pipeline(ctx, cmds...)
+		if err != nil {
+			return err
+		}
+		args := []interface{}{"SREM", set}
+		for i, reply := range replies {
+			if n, _ := reply.(int64); n == 0 {
+				args = append(args, members[i])
+			}
+		}
+		if len(args) > 2 {
+			if _, err := c.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Set 设置缓存值，有标签时以流水线同时写入标签的集合
+func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		c.errors.Add(1)
+		return err
+	}
+	cmds := [][]interface{}{c.setArgs(key, data, ttl)}
+	cmds = append(cmds, c.tagArgs(newSetOptions(opts).tags, []interface{}{c.key(key)}, ttl)...)
+	if _, err := c.pipeline(ctx, cmds...); err != nil {
+		return err
+	}
+	c.sets.Add(1)
+	return nil
+}
+
+// Delete 删除缓存值
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	reply, err := c.do(ctx, "DEL", c.key(key))
+	if err != nil {
+		return err
+	}
+	if n, _ := reply.(int64); n > 0 {
+		c.deletes.Add(n)
+	}
+	return nil
+}
+
+// Exists 检查key是否存在
+func (c *RedisCache) Exists(ctx context.Context, key string) bool {
+	reply, err := c.do(ctx, "EXISTS", c.key(key))
+	if err != nil {
+		return false
+	}
+	n, _ := reply.(int64)
+	return n > 0
+}
+
+// TTLs 以PTTL批量获取存在的key的剩余TTL，不过期的key为0
+func (c *RedisCache) TTLs(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration, len(keys))
+	if len(keys) == 0 {
+		return ttls, nil
+	}
+	cmds := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		cmds[i] = []interface{}{"PTTL", c.key(key)}
+	}
+	replies, err := c.pipeline(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	for i, reply := range replies {
+		// -2 不存在，-1 不过期
+		switch ms, _ := reply.(int64); {
+		case ms == -1:
+			ttls[keys[i]] = 0
+		case ms > 0:
+			ttls[keys[i]] = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return ttls, nil
+}
+
+// GetMany 以MGET批量获取，结果只包含存在的key
+func (c *RedisCache) GetMany(ctx context.Context, keys []string) (Values, error) {
+	values := newValues(len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, "MGET")
+	for _, key := range keys {
+		args = append(args, c.key(key))
+	}
+	reply, err := c.do(ctx, args...)
+	if err != nil {
+		return values, err
+	}
+	items, _ := reply.([]interface{})
+	for i, item := range items {
+		if data, ok := item.([]byte); ok && i < len(keys) {
+			values.add(keys[i], data, c.codec)
+			c.hits.Add(1)
+		} else {
+			c.misses.Add(1)
+		}
+	}
+	return values, nil
+}
+
+// SetMany 批量设置，ttl为0时使用MSET，否则以流水线发送SET
+func (c *RedisCache) SetMany(ctx context.Context, items map[string]interface{}, ttl time.Duration, opts ...SetOption) error {
+	if len(items) == 0 {
+		return nil
+	}
+	encoded := make(map[string][]byte, len(items))
+	keys := make([]interface{}, 0, len(items))
+	for key, value := range items {
+		data, err := c.codec.Marshal(value)
+		if err != nil {
+			c.errors.Add(1)
+			return err
+		}
+		encoded[key] = data
+		keys = append(keys, c.key(key))
+	}
+
+	var cmds [][]interface{}
+	if ttl <= 0 {
+		args := make([]interface{}, 0, 2*len(items)+1)
+		args = append(args, "MSET")
+		for key, data := range encoded {
+			args = append(args, c.key(key), data)
+		}
+		cmds = append(cmds, args)
+	} else {
+		for key, data := range encoded {
+			cmds = append(cmds, c.setArgs(key, data, ttl))
+		}
+	}
+	cmds = append(cmds, c.tagArgs(newSetOptions(opts).tags, keys, ttl)...)
+	if _, err := c.pipeline(ctx, cmds...); err != nil {
+		return err
+	}
+	c.sets.Add(int64(len(items)))
+	return nil
+}
+
+// DeleteMany 批量删除
+func (c *RedisCache) DeleteMany(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, c.key(key))
+	}
+	reply, err := c.do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := reply.(int64); n > 0 {
+		c.deletes.Add(n)
+	}
+	return nil
+}
+
+// DeletePrefix 以SCAN删除以prefix开头的key
+func (c *RedisCache) DeletePrefix(ctx context.Context, prefix string) error {
+	return c.scanDelete(ctx, globEscape(c.key(prefix))+"*")
+}
+
+// InvalidateTags 读取标签的集合，删除其中的key和集合
+func (c *RedisCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	cmds := make([][]interface{}, 0, len(tags))
+	for _, tag := range tags {
+		cmds = append(cmds, []interface{}{"SMEMBERS", c.tagKey(tag)})
+	}
+	replies, err := c.pipeline(ctx, cmds...)
+	if err != nil {
+		return err
+	}
+
+	var keys []interface{}
+	var sets int64
+	for _, reply := range replies {
+		members, _ := reply.([]interface{})
+		keys = append(keys, members...)
+		if len(members) > 0 {
+			sets++
+		}
+	}
+	args := make([]interface{}, 0, len(keys)+len(tags)+1)
+	args = append(args, "DEL")
+	args = append(args, keys...)
+	for _, tag := range tags {
+		args = append(args, c.tagKey(tag))
+	}
+	reply, err := c.do(ctx, args...)
+	if err != nil {
+		return err
+	}
+	// 集合中可能有已经删除或过期的key，只计入实际删除的key
+	if n, _ := reply.(int64); n > sets {
+		c.deletes.Add(n - sets)
+	}
+	return nil
+}
+
+// Keys 以SCAN列出以prefix开头的key中排序最前的limit个，并以流水线读取剩余的过期时间和大小，不包括标签的集合，
+// 扫描到的标签的集合会删除其中已经不存在的key
+func (c *RedisCache) Keys(ctx context.Context, prefix string, limit int) ([]KeyInfo, error) {
+	match := globEscape(c.key(prefix)) + "*"
+	tagPrefix := c.tagKey("")
+	var keys, sets []string
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, "SCAN", cursor, "MATCH", match, "COUNT", 1000)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := reply.([]interface{})
+		if len(items) != 2 {
+			return nil, RedisError("cache: unexpected SCAN reply")
+		}
+		next, _ := items[0].([]byte)
+		members, _ := items[1].([]interface{})
+		for _, member := range members {
+			key, _ := member.([]byte)
+			if strings.HasPrefix(string(key), tagPrefix) {
+				sets = append(sets, string(key))
+			} else {
+				keys = append(keys, string(key))
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			break
+		}
+	}
+	if err := c.pruneTags(ctx, sets); err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([][]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, []interface{}{"PTTL", key}, []interface{}{"STRLEN", key})
+	}
+	replies, err := c.pipeline(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]KeyInfo, 0, len(keys))
+	for i, key := range keys {
+		info := KeyInfo{Key: strings.TrimPrefix(key, c.prefix)}
+		if ms, _ := replies[2*i].(int64); ms > 0 {
+			info.TTL = (time.Duration(ms) * time.Millisecond).String()
+		}
+		info.Size, _ = replies[2*i+1].(int64)
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Clear 清空缓存，有Prefix时以SCAN删除前缀下的key，否则开启了FlushDB时清空整个数据库，
+// 未开启时返回ErrFlushDisabled
+func (c *RedisCache) Clear(ctx context.Context) error {
+	if c.prefix == "" {
+		if !c.flush {
+			return ErrFlushDisabled
+		}
+		_, err := c.do(ctx, "FLUSHDB")
+		return err
+	}
+	return c.scanDelete(ctx, globEscape(c.prefix)+"*")
+}
+
+// scanDelete 以SCAN遍历匹配的key并删除
+func (c *RedisCache) scanDelete(ctx context.Context, match string) error {
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, "SCAN", cursor, "MATCH", match, "COUNT", 1000)
+		if err != nil {
+			return err
+		}
+		items, _ := reply.([]interface{})
+		if len(items) != 2 {
+			return RedisError("cache: unexpected SCAN reply")
+		}
+		next, _ := items[0].([]byte)
+		keys, _ := items[1].([]interface{})
+		if len(keys) > 0 {
+			args := make([]interface{}, 0, len(keys)+1)
+			args = append(args, "DEL")
+			args = append(args, keys...)
+			if _, err := c.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// globEscape 转义SCAN MATCH的特殊字符
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Stats 获取统计信息，没有Prefix时，即独占数据库时，从INFO读取key数、内存、淘汰和过期数；
+// 有Prefix时统计前缀下的key数需要遍历整个数据库，不适合每次采集都执行，key数、内存、淘汰和过期数为0
+func (c *RedisCache) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Sets:    c.sets.Load(),
+		Deletes: c.deletes.Load(),
+		Errors:  c.errors.Load(),
+	}
+	total := stats.Hits + stats.Misses
+	if total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	if c.prefix != "" || c.closed.Load() {
+		return stats
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	replies, err := c.pool.do(ctx, []interface{}{"INFO"}, []interface{}{"DBSIZE"})
+	if err != nil {
+		return stats
+	}
+	if info, ok := replies[0].([]byte); ok {
+		for _, line := range bytes.Split(info, []byte("\n")) {
+			k, v, ok := strings.Cut(strings.TrimSpace(string(line)), ":")
+			if !ok {
+				continue
+			}
+			n, _ := strconv.ParseInt(v, 10, 64)
+			switch k {
+			case "used_memory":
+				stats.MemoryUsage = n
+			case "evicted_keys":
+				stats.Evictions = n
+			case "expired_keys":
+				stats.Expirations = n
+			}
+		}
+	}
+	if n, ok := replies[1].(int64); ok {
+		stats.TotalKeys = int(n)
+	}
+	return stats
+}
+
+// Close 关闭缓存
+func (c *RedisCache) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.pool.close()
+	}
+	return nil
+}
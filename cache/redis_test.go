@@ -0,0 +1,548 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRedis 进程内的RESP服务，支持缓存用到的命令
+type fakeRedis struct {
+	mu       sync.Mutex
+	items    map[string]fakeItem
+	sets     map[string]map[string]bool
+	setTTL   map[string]time.Time
+	dials    atomic.Int32
+	commands atomic.Int32
+	password string
+}
+
+type fakeItem struct {
+	value    []byte
+	expireAt time.Time
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{items: make(map[string]fakeItem), sets: make(map[string]map[string]bool),
+		setTTL: make(map[string]time.Time)}
+}
+
+// dial 连接工厂，以net.Pipe连接到服务
+func (f *fakeRedis) dial(ctx context.Context) (net.Conn, error) {
+	f.dials.Add(1)
+	client, server := net.Pipe()
+	go f.serve(server)
+	return client, nil
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	br, bw := bufio.NewReader(conn), bufio.NewWriter(conn)
+	for {
+		reply, err := readReply(br)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		f.commands.Add(1)
+		writeReply(bw, f.exec(args))
+		// 流水线中的命令读完后再刷新
+		if br.Buffered() == 0 {
+			if err := bw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		w.WriteString("+" + v + "\r\n")
+	case RedisError:
+		w.WriteString("-" + string(v) + "\r\n")
+	case int:
+		w.WriteString(":" + strconv.Itoa(v) + "\r\n")
+	case []byte:
+		w.WriteString("$" + strconv.Itoa(len(v)) + "\r\n")
+		w.Write(v)
+		w.WriteString("\r\n")
+	case []interface{}:
+		w.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	}
+}
+
+// get 需要持有锁
+func (f *fakeRedis) get(key string) ([]byte, bool) {
+	item, ok := f.items[key]
+	if ok && !item.expireAt.IsZero() && time.Now().After(item.expireAt) {
+		delete(f.items, key)
+		return nil, false
+	}
+	return item.value, ok
+}
+
+// set the members of the set, nil if not exists or expired
+func (f *fakeRedis) set(key string) map[string]bool {
+	if at, ok := f.setTTL[key]; ok && time.Now().After(at) {
+		delete(f.sets, key)
+		delete(f.setTTL, key)
+	}
+	return f.sets[key]
+}
+
+// tag the tagScript
+func (f *fakeRedis) tag(key string, ms int, members []string) {
+	existed := f.set(key) != nil
+	if !existed {
+		f.sets[key] = make(map[string]bool)
+	}
+	for _, member := range members {
+		f.sets[key][member] = true
+	}
+	ttl := time.Duration(ms) * time.Millisecond
+	switch at, expiring := f.setTTL[key]; {
+	case ms == 0:
+		delete(f.setTTL, key)
+	case !existed || expiring && time.Until(at) < ttl:
+		f.setTTL[key] = time.Now().Add(ttl)
+	}
+}
+
+func (f *fakeRedis) exec(args []string) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "PONG"
+	case "AUTH":
+		if args[1] != f.password {
+			return RedisError("WRONGPASS invalid password")
+		}
+		return "OK"
+	case "SELECT", "FLUSHDB":
+		if args[0] == "FLUSHDB" {
+			f.items = make(map[string]fakeItem)
+			f.sets = make(map[string]map[string]bool)
+			f.setTTL = make(map[string]time.Time)
+		}
+		return "OK"
+	case "GET":
+		if v, ok := f.get(args[1]); ok {
+			return v
+		}
+		return nil
+	case "SET":
+		item := fakeItem{value: []byte(args[2])}
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			item.expireAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		f.items[args[1]] = item
+		return "OK"
+	case "MSET":
+		for i := 1; i+1 < len(args); i += 2 {
+			f.items[args[i]] = fakeItem{value: []byte(args[i+1])}
+		}
+		return "OK"
+	case "MGET":
+		values := make([]interface{}, 0, len(args)-1)
+		for _, key := range args[1:] {
+			if v, ok := f.get(key); ok {
+				values = append(values, v)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		return values
+	case "DEL", "EXISTS":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := f.get(key); ok {
+				n++
+				if args[0] == "DEL" {
+					delete(f.items, key)
+				}
+			} else if f.set(key) != nil {
+				n++
+				if args[0] == "DEL" {
+					delete(f.sets, key)
+					delete(f.setTTL, key)
+				}
+			}
+		}
+		return n
+	case "SADD":
+		if f.sets[args[1]] == nil {
+			f.sets[args[1]] = make(map[string]bool)
+		}
+		for _, member := range args[2:] {
+			f.sets[args[1]][member] = true
+		}
+		return len(args) - 2
+	case "EVAL":
+		if args[1] != tagScript {
+			return RedisError("ERR unknown script")
+		}
+		ms, _ := strconv.Atoi(args[4])
+		f.tag(args[3], ms, args[5:])
+		return 1
+	case "SREM":
+		n := 0
+		for _, member := range args[2:] {
+			if f.set(args[1])[member] {
+				delete(f.sets[args[1]], member)
+				n++
+			}
+		}
+		if f.sets[args[1]] != nil && len(f.sets[args[1]]) == 0 {
+			delete(f.sets, args[1])
+			delete(f.setTTL, args[1])
+		}
+		return n
+	case "SMEMBERS":
+		members := []interface{}{}
+		for member := range f.set(args[1]) {
+			members = append(members, []byte(member))
+		}
+		return members
+	case "SCAN":
+		var keys []interface{}
+		for key := range f.items {
+			if ok, _ := path.Match(args[3], key); ok {
+				keys = append(keys, []byte(key))
+			}
+		}
+		for key := range f.sets {
+			if ok, _ := path.Match(args[3], key); ok && f.set(key) != nil {
+				keys = append(keys, []byte(key))
+			}
+		}
+		return []interface{}{[]byte("0"), keys}
+	case "PTTL":
+		if f.set(args[1]) != nil {
+			if at, ok := f.setTTL[args[1]]; ok {
+				return int(time.Until(at).Milliseconds())
+			}
+			return -1
+		}
+		item, ok := f.items[args[1]]
+		switch {
+		case !ok:
+			return -2
+		case item.expireAt.IsZero():
+			return -1
+		}
+		return int(time.Until(item.expireAt).Milliseconds())
+	case "STRLEN":
+		return len(f.items[args[1]].value)
+	case "DBSIZE":
+		return len(f.items)
+	case "INFO":
+		return []byte("# Memory\r\nused_memory:1024\r\n# Stats\r\nexpired_keys:2\r\nevicted_keys:1\r\n")
+	default:
+		return RedisError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	f.password = "secret"
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, Password: "secret", DB: 1, Prefix: "app:"})
+	defer c.Close()
+
+	if err := c.Set(ctx, "a", testValue{"x"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	var v testValue
+	if err := c.Get(ctx, "a", &v); err != nil || v.V != "x" {
+		t.Fatalf("get a: %v %v", v, err)
+	}
+	if _, ok := f.items["app:a"]; !ok {
+		t.Error("expect the key to be prefixed")
+	}
+	if err := c.Get(ctx, "b", &v); err != ErrKeyNotFound {
+		t.Errorf("expect not found, got %v", err)
+	}
+	if !c.Exists(ctx, "a") || c.Exists(ctx, "b") {
+		t.Error("unexpected exists")
+	}
+
+	_ = c.Set(ctx, "ttl", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if c.Exists(ctx, "ttl") {
+		t.Error("expect the key to be expired")
+	}
+
+	_ = c.Delete(ctx, "a")
+	if c.Exists(ctx, "a") {
+		t.Error("expect the key to be deleted")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Sets != 2 || s.Deletes != 1 || s.TotalKeys != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if n := f.dials.Load(); n != 1 {
+		t.Errorf("expect the connection to be reused, dialed %d", n)
+	}
+}
+
+func TestRedisCacheMany(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, Prefix: "app:"})
+	defer c.Close()
+
+	_ = c.SetMany(ctx, map[string]interface{}{"a": 1, "b": 2}, 0)
+	_ = c.SetMany(ctx, map[string]interface{}{"c": 3, "d": 4}, time.Minute)
+	if f.items["app:c"].expireAt.IsZero() || !f.items["app:a"].expireAt.IsZero() {
+		t.Error("unexpected ttl")
+	}
+	values, err := c.GetMany(ctx, []string{"a", "c", "x"})
+	if err != nil || values.Len() != 2 || values.Has("x") {
+		t.Fatalf("unexpected values %v %v", values.Keys(), err)
+	}
+	var n int
+	if err := values.Decode("c", &n); err != nil || n != 3 {
+		t.Errorf("decode c: %v %v", n, err)
+	}
+	if err := values.Decode("x", &n); err != ErrKeyNotFound {
+		t.Errorf("expect not found, got %v", err)
+	}
+
+	_ = c.DeleteMany(ctx, []string{"a", "b"})
+	f.items["other"] = fakeItem{value: []byte("1")}
+	if c.Exists(ctx, "a") || !c.Exists(ctx, "c") {
+		t.Error("unexpected exists after delete")
+	}
+	// 只清除前缀下的key
+	_ = c.Clear(ctx)
+	if len(f.items) != 1 {
+		t.Errorf("expect only the other key left, got %v", len(f.items))
+	}
+	if s := c.Stats(); s.Sets != 4 || s.Hits != 2 || s.Misses != 1 || s.Deletes != 2 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestRedisCacheTagSets(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, Prefix: "app:"})
+	defer c.Close()
+	ttl := func(tag string) time.Duration {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.set("app:__tag__:"+tag) == nil {
+			return -2
+		}
+		if at, ok := f.setTTL["app:__tag__:"+tag]; ok {
+			return time.Until(at)
+		}
+		return -1
+	}
+
+	// the set lives as long as the longest member, forever with a member without TTL
+	_ = c.Set(ctx, "a", 1, time.Hour, WithTags("t"))
+	_ = c.Set(ctx, "b", 1, time.Minute, WithTags("t"))
+	_ = c.SetMany(ctx, map[string]interface{}{"c": 1, "d": 1}, time.Minute, WithTags("u"))
+	_ = c.Set(ctx, "e", 1, 0, WithTags("v"))
+	_ = c.Set(ctx, "f", 1, time.Minute, WithTags("v"))
+	if d := ttl("t"); d < 59*time.Minute {
+		t.Errorf("expect the set of t to live for an hour, got %v", d)
+	}
+	if d := ttl("u"); d <= 0 || d > time.Minute {
+		t.Errorf("expect the set of u to expire in a minute, got %v", d)
+	}
+	if d := ttl("v"); d != -1 {
+		t.Errorf("expect the set of v not to expire, got %v", d)
+	}
+
+	// deleted members are pruned by Keys and not counted by InvalidateTags
+	_ = c.Delete(ctx, "e")
+	_ = c.DeleteMany(ctx, []string{"c", "d"})
+	if _, err := c.Keys(ctx, "", 0); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	if members := f.set("app:__tag__:v"); len(members) != 1 || !members["app:f"] {
+		t.Errorf("expect only f in the set of v, got %v", members)
+	}
+	if f.set("app:__tag__:u") != nil {
+		t.Error("expect the empty set of u to be removed")
+	}
+	f.mu.Unlock()
+	_ = c.Delete(ctx, "b")
+	_ = c.InvalidateTags(ctx, "t")
+	if s := c.Stats(); s.Deletes != 5 || c.Exists(ctx, "a") {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestRedisCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	f.password = "secret"
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, Password: "wrong"})
+	if err := c.Set(ctx, "a", 1, 0); err == nil || !strings.HasPrefix(err.Error(), "WRONGPASS") {
+		t.Errorf("expect auth error, got %v", err)
+	}
+	if s := c.Stats(); s.Errors != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	_ = c.Close()
+	if err := c.Set(ctx, "a", 1, 0); err != ErrCacheClosed {
+		t.Errorf("expect closed, got %v", err)
+	}
+}
+
+func TestRedisCacheStats(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial})
+	defer c.Close()
+
+	_ = c.Set(ctx, "a", 1, 0)
+	s := c.Stats()
+	if s.TotalKeys != 1 || s.MemoryUsage != 1024 || s.Expirations != 2 || s.Evictions != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if err := c.Clear(ctx); err != ErrFlushDisabled || len(f.items) != 1 {
+		t.Errorf("expect the db not flushed without FlushDB, got %v", err)
+	}
+	flush := NewRedisCache(RedisCacheConfig{Dial: f.dial, FlushDB: true})
+	defer flush.Close()
+	_ = flush.Clear(ctx)
+	if len(f.items) != 0 {
+		t.Error("expect the db to be flushed")
+	}
+
+	// the keys under the prefix are not counted, the db is not scanned on every scrape
+	p := NewRedisCache(RedisCacheConfig{Dial: f.dial, Prefix: "app:"})
+	defer p.Close()
+	_ = p.Set(ctx, "a", 1, 0)
+	if s := p.Stats(); s.TotalKeys != 0 || s.MemoryUsage != 0 || s.Sets != 1 {
+		t.Errorf("unexpected stats with prefix %+v", s)
+	}
+}
+
+func TestRedisCacheConcurrency(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, PoolSize: 4})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("k%d", i)
+				_ = c.Set(ctx, key, i, 0)
+				var n int
+				if err := c.Get(ctx, key, &n); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := f.dials.Load(); n > 4 {
+		t.Errorf("expect at most 4 connections, dialed %d", n)
+	}
+}
+
+func TestRedisCacheTags(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, Prefix: "app:"})
+	defer c.Close()
+
+	_ = c.Set(ctx, "user:42:profile", 1, time.Minute, WithTags("user:42"))
+	_ = c.SetMany(ctx, map[string]interface{}{"user:42:orders": 2, "user:43:orders": 3}, 0, WithTags("orders"))
+	_ = c.Set(ctx, "user:43:profile", 4, 0, WithTags("user:43"))
+	commands := f.commands.Load()
+	_ = c.Set(ctx, "user:44:profile", 5, 0, WithTags("user:44", "vip"))
+	if n := f.commands.Load() - commands; n != 3 {
+		t.Errorf("expect SET and two SADD, got %d commands", n)
+	}
+
+	if err := c.InvalidateTags(ctx, "user:42", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{
+		"user:42:profile": false, "user:42:orders": false, "user:43:orders": false, "user:43:profile": true,
+	} {
+		if got := c.Exists(ctx, key); got != want {
+			t.Errorf("exists %s = %v, want %v", key, got, want)
+		}
+	}
+	if _, ok := f.sets["app:__tag__:orders"]; ok {
+		t.Error("expect the tag set to be deleted")
+	}
+
+	_ = c.DeletePrefix(ctx, "user:4")
+	if c.Exists(ctx, "user:43:profile") || c.Exists(ctx, "user:44:profile") {
+		t.Error("expect the prefix to be deleted")
+	}
+}
+
+func TestRedisCacheKeys(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, Prefix: "app:"})
+	defer c.Close()
+
+	_ = c.Set(ctx, "user:1", 1, time.Hour, WithTags("users"))
+	_ = c.Set(ctx, "user:2", 22, 0)
+	_ = c.Set(ctx, "team:1", 1, 0)
+	keys, err := c.Keys(ctx, "user:", 10)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("unexpected keys %v %v", keys, err)
+	}
+	if keys[0].Key != "user:1" || keys[0].TTL == "" || keys[1].Key != "user:2" || keys[1].Size != 2 || keys[1].TTL != "" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+	if keys, _ := c.Keys(ctx, "", 0); len(keys) != 3 {
+		t.Errorf("expect the tag sets to be excluded, got %+v", keys)
+	}
+	if keys, _ := c.Keys(ctx, "", 1); len(keys) != 1 || keys[0].Key != "team:1" {
+		t.Errorf("expect the first key, got %+v", keys)
+	}
+}
+
+func TestRedisCacheTTLs(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	c := NewRedisCache(RedisCacheConfig{Dial: f.dial, Prefix: "app:"})
+	defer c.Close()
+
+	_ = c.Set(ctx, "a", 1, time.Minute)
+	_ = c.Set(ctx, "b", 1, 0)
+	ttls, err := c.TTLs(ctx, []string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ttls) != 2 || ttls["a"] <= 0 || ttls["a"] > time.Minute || ttls["b"] != 0 {
+		t.Errorf("unexpected ttls %v", ttls)
+	}
+}
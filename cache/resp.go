@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RedisError Redis返回的错误回复，如 "ERR unknown command"
+type RedisError string
+
+func (e RedisError) Error() string { return string(e) }
+
+var errPoolClosed = errors.New("cache: redis pool is closed")
+
+// redisConn RESP连接
+type redisConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	bw   *bufio.Writer
+}
+
+// newRedisConn
+func newRedisConn(conn net.Conn) *redisConn {
+	return &redisConn{conn: conn, br: bufio.NewReader(conn), bw: bufio.NewWriter(conn)}
+}
+
+// pipeline 一次写入所有命令后按顺序读取回复，命令的错误回复以RedisError返回在回复中，
+// 只有网络或协议错误才返回error，此时连接不可再用
+func (c *redisConn) pipeline(deadline time.Time, cmds ...[]interface{}) ([]interface{}, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	for _, args := range cmds {
+		if err := writeCommand(c.bw, args); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.bw.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := readReply(c.br)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// writeCommand 以RESP数组写入命令
+func writeCommand(w *bufio.Writer, args []interface{}) error {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(len(args)))
+	w.WriteString("\r\n")
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		default:
+			return fmt.Errorf("cache: unsupported redis argument %T", arg)
+		}
+		w.WriteByte('$')
+		w.WriteString(strconv.Itoa(len(b)))
+		w.WriteString("\r\n")
+		w.Write(b)
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readReply 读取RESP回复：简单字符串为string，错误为RedisError，整数为int64，
+// 批量字符串为[]byte，数组为[]interface{}，空值为nil
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("cache: empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return RedisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("cache: unexpected redis reply %q", line)
+	}
+}
+
+// readLine 读取去掉\r\n的一行
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("cache: malformed redis reply %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// redisPool 连接池，最多size个连接
+type redisPool struct {
+	dial    func(ctx context.Context) (net.Conn, error)
+	init    func(c *redisConn, deadline time.Time) error
+	timeout time.Duration
+	idle    chan *redisConn
+	sem     chan struct{}
+	closed  atomic.Bool
+	mu      sync.Mutex
+}
+
+// newRedisPool
+func newRedisPool(size int, timeout time.Duration, dial func(ctx context.Context) (net.Conn, error),
+	init func(c *redisConn, deadline time.Time) error) *redisPool {
+	return &redisPool{
+		dial:    dial,
+		init:    init,
+		timeout: timeout,
+		idle:    make(chan *redisConn, size),
+		sem:     make(chan struct{}, size),
+	}
+}
+
+// deadline 请求的截止时间，取ctx和超时中较早的
+func (p *redisPool) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// get 获取空闲连接，没有时新建，达到上限时等待
+func (p *redisPool) get(ctx context.Context) (*redisConn, error) {
+	if p.closed.Load() {
+		return nil, errPoolClosed
+	}
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+	select {
+	case c := <-p.idle:
+		return c, nil
+	case p.sem <- struct{}{}:
+		conn, err := p.dial(ctx)
+		if err != nil {
+			<-p.sem
+			return nil, err
+		}
+		c := newRedisConn(conn)
+		if p.init != nil {
+			if err := p.init(c, p.deadline(ctx)); err != nil {
+				conn.Close()
+				<-p.sem
+				return nil, err
+			}
+		}
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// put 归还连接，出错的连接直接关闭
+func (p *redisPool) put(c *redisConn, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || p.closed.Load() {
+		c.conn.Close()
+		<-p.sem
+		return
+	}
+	p.idle <- c
+}
+
+// do 在一个连接上执行流水线
+func (p *redisPool) do(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error) {
+	c, err := p.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := c.pipeline(p.deadline(ctx), cmds...)
+	p.put(c, err)
+	return replies, err
+}
+
+// close 关闭空闲连接，使用中的连接归还时关闭
+func (p *redisPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed.CompareAndSwap(false, true) {
+		return
+	}
+	for {
+		select {
+		case c := <-p.idle:
+			c.conn.Close()
+			<-p.sem
+		default:
+			return
+		}
+	}
+}
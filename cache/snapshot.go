@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotMagic 快照文件头，末尾为格式版本
+const snapshotMagic = "GINNYCS1"
+
+// Snapshot 把未过期的条目写入MemoryCacheConfig.SnapshotPath，先写临时文件再重命名，
+// 写入过程中的失败不会破坏已有的快照
+func (c *MemoryCache) Snapshot() error {
+	if c.snapshotPath == "" {
+		return errors.New("cache: snapshot path is not configured")
+	}
+	c.snapshotMu.Lock()
+	defer c.snapshotMu.Unlock()
+	return c.writeSnapshot()
+}
+
+// writeSnapshot 需要持有snapshotMu
+func (c *MemoryCache) writeSnapshot() error {
+	dir := filepath.Dir(c.snapshotPath)
+	f, err := os.CreateTemp(dir, filepath.Base(c.snapshotPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	w.WriteString(snapshotMagic)
+	writeBytes(w, []byte(c.codec.Name()))
+	err = c.store.snapshot(func(entries []snapshotEntry[string, []byte]) error {
+		for _, e := range entries {
+			w.WriteByte(1)
+			writeBytes(w, []byte(e.key))
+			writeBytes(w, e.value)
+			w.Write(binary.AppendVarint(nil, e.expireAt))
+			w.Write(binary.AppendUvarint(nil, uint64(len(e.tags))))
+			for _, tag := range e.tags {
+				writeBytes(w, []byte(tag))
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		// 结束标记，没有结束标记的快照视为不完整
+		w.WriteByte(0)
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.snapshotPath)
+}
+
+// writeBytes 写入长度和内容
+func writeBytes(w *bufio.Writer, b []byte) {
+	w.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	w.Write(b)
+}
+
+// loadSnapshot 加载快照，跳过已过期的条目，文件不存在时忽略
+func (c *MemoryCache) loadSnapshot() error {
+	f, err := os.Open(c.snapshotPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != snapshotMagic {
+		return fmt.Errorf("cache: invalid snapshot %s", c.snapshotPath)
+	}
+	codec, err := readBytes(r)
+	if err != nil {
+		return err
+	}
+	if string(codec) != c.codec.Name() {
+		return fmt.Errorf("cache: snapshot codec %s does not match %s", codec, c.codec.Name())
+	}
+
+	// 先读完整个快照，不完整时不加载
+	var entries []snapshotEntry[string, []byte]
+	for {
+		flag, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("cache: truncated snapshot %s: %w", c.snapshotPath, err)
+		}
+		if flag == 0 {
+			break
+		}
+		e, err := readSnapshotEntry(r)
+		if err != nil {
+			return fmt.Errorf("cache: truncated snapshot %s: %w", c.snapshotPath, err)
+		}
+		entries = append(entries, e)
+	}
+	for _, e := range entries {
+		c.store.restore(e, entrySize(e.key, e.value, e.tags))
+	}
+	return nil
+}
+
+// readSnapshotEntry
+func readSnapshotEntry(r *bufio.Reader) (e snapshotEntry[string, []byte], err error) {
+	key, err := readBytes(r)
+	if err != nil {
+		return e, err
+	}
+	if e.value, err = readBytes(r); err != nil {
+		return e, err
+	}
+	if e.expireAt, err = binary.ReadVarint(r); err != nil {
+		return e, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return e, err
+	}
+	for i := uint64(0); i < n; i++ {
+		tag, err := readBytes(r)
+		if err != nil {
+			return e, err
+		}
+		e.tags = append(e.tags, string(tag))
+	}
+	e.key = string(key)
+	return e, nil
+}
+
+// readBytes 读取长度和内容
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > 1<<30 {
+		return nil, errors.New("cache: snapshot entry too large")
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+// snapshotter 定期写入快照
+func (c *MemoryCache) snapshotter(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.snapshotMu.Lock()
+			if !c.store.closed.Load() {
+				if err := c.writeSnapshot(); err != nil {
+					c.store.errors.Add(1)
+				}
+			}
+			c.snapshotMu.Unlock()
+		case <-c.stopSnapshot:
+			return
+		}
+	}
+}
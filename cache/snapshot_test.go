@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	c := NewMemoryCache(MemoryCacheConfig{SnapshotPath: path})
+
+	_ = c.Set(ctx, "forever", testValue{"x"}, 0)
+	_ = c.Set(ctx, "hour", 1, time.Hour, WithTags("user:42"))
+	_ = c.Set(ctx, "short", 2, 30*time.Millisecond)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	c = NewMemoryCache(MemoryCacheConfig{SnapshotPath: path})
+	defer c.Close()
+	var v testValue
+	if err := c.Get(ctx, "forever", &v); err != nil || v.V != "x" {
+		t.Errorf("get forever: %v %v", v, err)
+	}
+	if c.Exists(ctx, "short") {
+		t.Error("expect the expired key to be skipped")
+	}
+	if s := c.Stats(); s.TotalKeys != 2 || s.Sets != 0 || s.Errors != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	// 保留过期时间和标签
+	sh, _ := c.store.shardOf("hour")
+	if e := sh.items["hour"]; e == nil || time.Until(time.Unix(0, e.expireAt)) < 59*time.Minute {
+		t.Error("expect the ttl to be preserved")
+	}
+	_ = c.InvalidateTags(ctx, "user:42")
+	if c.Exists(ctx, "hour") {
+		t.Error("expect the tags to be preserved")
+	}
+}
+
+func TestMemoryCacheSnapshotInterval(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	c := NewMemoryCache(MemoryCacheConfig{SnapshotPath: path, SnapshotInterval: 10 * time.Millisecond})
+	_ = c.Set(ctx, "k", 1, 0)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expect a periodic snapshot")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 模拟进程崩溃，没有调用Close
+	other := NewMemoryCache(MemoryCacheConfig{SnapshotPath: path})
+	defer other.Close()
+	if !other.Exists(ctx, "k") {
+		t.Error("expect the key to be loaded")
+	}
+	_ = c.Close()
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) != 0 {
+		t.Errorf("unexpected temporary files %v", matches)
+	}
+}
+
+func TestMemoryCacheSnapshotInvalid(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "corrupted")
+	_ = os.WriteFile(path, []byte("not a snapshot"), 0o644)
+	c := NewMemoryCache(MemoryCacheConfig{SnapshotPath: path})
+	if s := c.Stats(); s.TotalKeys != 0 || s.Errors != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	_ = c.Close()
+
+	// 截断的快照不加载
+	path = filepath.Join(dir, "truncated")
+	c = NewMemoryCache(MemoryCacheConfig{SnapshotPath: path})
+	_ = c.Set(ctx, "a", 1, 0)
+	_ = c.Set(ctx, "b", 2, 0)
+	_ = c.Close()
+	data, _ := os.ReadFile(path)
+	_ = os.WriteFile(path, data[:len(data)-3], 0o644)
+	c = NewMemoryCache(MemoryCacheConfig{SnapshotPath: path})
+	if s := c.Stats(); s.TotalKeys != 0 || s.Errors != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	_ = c.Close()
+
+	// 编码不同时不加载
+	c = NewMemoryCache(MemoryCacheConfig{SnapshotPath: path, Codec: GobCodec})
+	defer c.Close()
+	if s := c.Stats(); s.TotalKeys != 0 || s.Errors != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
@@ -0,0 +1,619 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// storeConfig
+type storeConfig struct {
+	maxSize         int64 // 最大容量，平均分配到各分片
+	entrySize       int64 // 估算的条目平均大小，用于TinyLFU的频率统计
+	cleanupInterval time.Duration
+	shards          int
+	policy          EvictionPolicy
+}
+
+// store 按key的哈希分片的存储，每个分片有独立的锁和淘汰策略，MemoryCache和Typed共用
+type store[K comparable, V any] struct {
+	shards []*shard[K, V]
+	mask   uint64
+	hash   func(K) uint64
+	closed atomic.Bool
+	errors atomic.Int64
+	evict  *listener[K, V]
+
+	// 清理相关
+	cleanupInterval time.Duration
+	stopCleanup     chan struct{}
+}
+
+// shard 缓存分片
+type shard[K comparable, V any] struct {
+	mu       sync.Mutex
+	items    map[K]*entry[K, V]
+	tags     map[string]map[K]*entry[K, V] // 标签索引
+	policy   policy[K, V]
+	bytes    int64
+	maxBytes int64
+	entries  int64
+	kind     EvictionPolicy
+	expiry   expiryHeap[K, V]
+	evict    *listener[K, V]
+	evicted  []evicted[K, V] // 等待回调的淘汰
+
+	hits        atomic.Int64
+	misses      atomic.Int64
+	sets        atomic.Int64
+	deletes     atomic.Int64
+	evictions   atomic.Int64
+	expirations atomic.Int64
+}
+
+// newStore
+func newStore[K comparable, V any](config storeConfig, hash func(K) uint64) *store[K, V] {
+	if config.cleanupInterval <= 0 {
+		config.cleanupInterval = time.Second
+	}
+	if config.shards <= 0 {
+		config.shards = 16
+	}
+	n := 1
+	for n < config.shards {
+		n <<= 1
+	}
+
+	s := &store[K, V]{
+		shards:          make([]*shard[K, V], n),
+		mask:            uint64(n - 1),
+		hash:            hash,
+		cleanupInterval: config.cleanupInterval,
+		stopCleanup:     make(chan struct{}),
+		evict:           &listener[K, V]{},
+	}
+	maxBytes := config.maxSize / int64(n)
+	if maxBytes < 1 {
+		maxBytes = 1
+	}
+	entries := maxBytes
+	if config.entrySize > 1 {
+		entries /= config.entrySize
+	}
+	for i := range s.shards {
+		s.shards[i] = &shard[K, V]{
+			items:    make(map[K]*entry[K, V]),
+			tags:     make(map[string]map[K]*entry[K, V]),
+			policy:   newPolicy[K, V](config.policy, maxBytes, entries),
+			maxBytes: maxBytes,
+			entries:  entries,
+			kind:     config.policy,
+			evict:    s.evict,
+		}
+	}
+
+	// 启动清理协程
+	go s.cleaner()
+
+	return s
+}
+
+// onEvict 设置淘汰回调，nil表示取消
+func (s *store[K, V]) onEvict(fn evictFunc[K, V]) {
+	if fn == nil {
+		s.evict.fn.Store(nil)
+		return
+	}
+	s.evict.fn.Store(&fn)
+}
+
+// shardOf
+func (s *store[K, V]) shardOf(key K) (*shard[K, V], uint64) {
+	h := s.hash(key)
+	return s.shards[h&s.mask], h
+}
+
+// get
+func (s *store[K, V]) get(key K) (V, error) {
+	var zero V
+	if s.closed.Load() {
+		return zero, ErrCacheClosed
+	}
+	sh, _ := s.shardOf(key)
+
+	sh.mu.Lock()
+	e, exists := sh.items[key]
+	if !exists {
+		sh.mu.Unlock()
+		sh.misses.Add(1)
+		return zero, ErrKeyNotFound
+	}
+	if e.expired(time.Now().UnixNano()) {
+		sh.removeEntry(e, EvictExpired)
+		sh.unlock()
+		sh.expirations.Add(1)
+		sh.misses.Add(1)
+		return zero, ErrKeyNotFound
+	}
+	sh.policy.access(e)
+	value := e.value
+	sh.mu.Unlock()
+
+	sh.hits.Add(1)
+	return value, nil
+}
+
+// item 批量写入的条目
+type item[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+// expireAt
+func expireAt(ttl time.Duration) int64 {
+	if ttl > 0 {
+		return time.Now().Add(ttl).UnixNano()
+	}
+	return 0
+}
+
+// set
+func (s *store[K, V]) set(key K, value V, size int64, ttl time.Duration, tags []string) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	sh, h := s.shardOf(key)
+	if size > sh.maxBytes {
+		// 写入会淘汰分片中的所有条目后再淘汰自己
+		s.errors.Add(1)
+		return ErrValueTooLarge
+	}
+	expire := expireAt(ttl)
+
+	sh.mu.Lock()
+	if s.closed.Load() {
+		sh.mu.Unlock()
+		return ErrCacheClosed
+	}
+	sh.setLocked(key, h, value, size, expire, tags)
+	sh.unlock()
+	sh.sets.Add(1)
+
+	return nil
+}
+
+// setMany 按分片批量写入，每个分片只加锁一次，大于分片容量的条目不写入并返回ErrValueTooLarge
+func (s *store[K, V]) setMany(items []item[K, V], ttl time.Duration, tags []string) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	var err error
+	expire := expireAt(ttl)
+	for i, group := range s.group(len(items), func(i int) K { return items[i].key }) {
+		if len(group) == 0 {
+			continue
+		}
+		sh := s.shards[i]
+		sh.mu.Lock()
+		if s.closed.Load() {
+			sh.mu.Unlock()
+			return ErrCacheClosed
+		}
+		var sets int64
+		for _, j := range group {
+			it := items[j]
+			if it.size > sh.maxBytes {
+				s.errors.Add(1)
+				err = ErrValueTooLarge
+				continue
+			}
+			sh.setLocked(it.key, s.hash(it.key), it.value, it.size, expire, tags)
+			sets++
+		}
+		sh.unlock()
+		sh.sets.Add(sets)
+	}
+	return err
+}
+
+// group 按分片分组，返回每个分片的下标
+func (s *store[K, V]) group(n int, key func(i int) K) [][]int {
+	groups := make([][]int, len(s.shards))
+	for i := 0; i < n; i++ {
+		j := s.hash(key(i)) & s.mask
+		groups[j] = append(groups[j], i)
+	}
+	return groups
+}
+
+// setLocked 写入条目，需要持有分片的锁
+func (sh *shard[K, V]) setLocked(key K, h uint64, value V, size, expireAt int64, tags []string) {
+	if e, exists := sh.items[key]; exists {
+		// 如果key已存在，更新大小后视为一次访问
+		sh.notify(e, EvictReplaced)
+		sh.bytes += size - e.size
+		resize(e, size)
+		e.value, e.expireAt = value, expireAt
+		sh.untag(e)
+		e.tags = tags
+		sh.tag(e)
+		sh.expiry.schedule(e)
+		sh.policy.access(e)
+	} else {
+		e = &entry[K, V]{key: key, hash: h, value: value, size: size, expireAt: expireAt, tags: tags, index: -1}
+		sh.items[key] = e
+		sh.bytes += size
+		sh.tag(e)
+		sh.expiry.schedule(e)
+		sh.policy.add(e)
+	}
+
+	// 超过分片的容量时按策略淘汰
+	for sh.bytes > sh.maxBytes {
+		victim := sh.policy.victim()
+		if victim == nil {
+			break
+		}
+		sh.removeEntry(victim, EvictCapacity)
+		sh.evictions.Add(1)
+	}
+}
+
+// tag 添加条目的标签索引
+func (sh *shard[K, V]) tag(e *entry[K, V]) {
+	for _, t := range e.tags {
+		m := sh.tags[t]
+		if m == nil {
+			m = make(map[K]*entry[K, V])
+			sh.tags[t] = m
+		}
+		m[e.key] = e
+	}
+}
+
+// untag 删除条目的标签索引
+func (sh *shard[K, V]) untag(e *entry[K, V]) {
+	for _, t := range e.tags {
+		if m := sh.tags[t]; m != nil {
+			delete(m, e.key)
+			if len(m) == 0 {
+				delete(sh.tags, t)
+			}
+		}
+	}
+}
+
+// removeEntry 删除条目，需要持有分片的锁
+func (sh *shard[K, V]) removeEntry(e *entry[K, V], reason EvictReason) {
+	delete(sh.items, e.key)
+	sh.untag(e)
+	sh.expiry.unschedule(e)
+	sh.policy.remove(e)
+	sh.bytes -= e.size
+	sh.notify(e, reason)
+}
+
+// notify 设置了淘汰回调时记录条目当前的值，需要持有分片的锁
+func (sh *shard[K, V]) notify(e *entry[K, V], reason EvictReason) {
+	if sh.evict.fn.Load() != nil {
+		sh.evicted = append(sh.evicted, evicted[K, V]{key: e.key, value: e.value, reason: reason})
+	}
+}
+
+// unlock 释放分片的锁后调用淘汰回调，回调中可以再访问缓存
+func (sh *shard[K, V]) unlock() {
+	pending := sh.evicted
+	sh.evicted = nil
+	sh.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	if fn := sh.evict.fn.Load(); fn != nil {
+		for _, ev := range pending {
+			(*fn)(ev.key, ev.value, ev.reason)
+		}
+	}
+}
+
+// delete
+func (s *store[K, V]) delete(key K) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	sh, _ := s.shardOf(key)
+
+	sh.mu.Lock()
+	if e, exists := sh.items[key]; exists {
+		sh.removeEntry(e, EvictDeleted)
+		sh.deletes.Add(1)
+	}
+	sh.unlock()
+
+	return nil
+}
+
+// getMany 按分片批量读取，每个分片只加锁一次，fn对存在的key调用
+func (s *store[K, V]) getMany(keys []K, fn func(key K, value V)) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	now := time.Now().UnixNano()
+	for i, group := range s.group(len(keys), func(i int) K { return keys[i] }) {
+		if len(group) == 0 {
+			continue
+		}
+		sh := s.shards[i]
+		var hits, misses, expirations int64
+		sh.mu.Lock()
+		for _, j := range group {
+			e, exists := sh.items[keys[j]]
+			switch {
+			case !exists:
+				misses++
+			case e.expired(now):
+				sh.removeEntry(e, EvictExpired)
+				expirations++
+				misses++
+			default:
+				sh.policy.access(e)
+				fn(e.key, e.value)
+				hits++
+			}
+		}
+		sh.unlock()
+		sh.hits.Add(hits)
+		sh.misses.Add(misses)
+		sh.expirations.Add(expirations)
+	}
+	return nil
+}
+
+// deleteMany 按分片批量删除
+func (s *store[K, V]) deleteMany(keys []K) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	for i, group := range s.group(len(keys), func(i int) K { return keys[i] }) {
+		if len(group) == 0 {
+			continue
+		}
+		sh := s.shards[i]
+		sh.mu.Lock()
+		for _, j := range group {
+			if e, exists := sh.items[keys[j]]; exists {
+				sh.removeEntry(e, EvictDeleted)
+				sh.deletes.Add(1)
+			}
+		}
+		sh.unlock()
+	}
+	return nil
+}
+
+// deleteFunc 逐个分片删除match的key
+func (s *store[K, V]) deleteFunc(match func(key K) bool) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for key, e := range sh.items {
+			if match(key) {
+				sh.removeEntry(e, EvictDeleted)
+				sh.deletes.Add(1)
+			}
+		}
+		sh.unlock()
+	}
+	return nil
+}
+
+// invalidateTags 按标签索引删除带有任一标签的key
+func (s *store[K, V]) invalidateTags(tags []string) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for _, t := range tags {
+			for _, e := range sh.tags[t] {
+				sh.removeEntry(e, EvictDeleted)
+				sh.deletes.Add(1)
+			}
+		}
+		sh.unlock()
+	}
+	return nil
+}
+
+// snapshotEntry 快照中的条目
+type snapshotEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt int64
+	tags     []string
+}
+
+// snapshot 逐个分片复制未过期的条目，不阻塞其它分片
+func (s *store[K, V]) snapshot(fn func(entries []snapshotEntry[K, V]) error) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	for _, sh := range s.shards {
+		now := time.Now().UnixNano()
+		sh.mu.Lock()
+		entries := make([]snapshotEntry[K, V], 0, len(sh.items))
+		for _, e := range sh.items {
+			if !e.expired(now) {
+				entries = append(entries, snapshotEntry[K, V]{key: e.key, value: e.value, expireAt: e.expireAt, tags: e.tags})
+			}
+		}
+		sh.mu.Unlock()
+		if err := fn(entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore 写入快照中的条目，保留原来的过期时间，不计入写入数
+func (s *store[K, V]) restore(e snapshotEntry[K, V], size int64) {
+	if e.expireAt > 0 && time.Now().UnixNano() >= e.expireAt {
+		return
+	}
+	sh, h := s.shardOf(e.key)
+	if size > sh.maxBytes {
+		return
+	}
+	sh.mu.Lock()
+	sh.setLocked(e.key, h, e.value, size, e.expireAt, e.tags)
+	sh.unlock()
+}
+
+// scan 逐个分片对match的未过期条目调用fn，持有分片的锁，fn返回false时停止
+func (s *store[K, V]) scan(match func(key K) bool, fn func(e *entry[K, V]) bool) error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	now := time.Now().UnixNano()
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for key, e := range sh.items {
+			if match(key) && !e.expired(now) && !fn(e) {
+				sh.mu.Unlock()
+				return nil
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return nil
+}
+
+// exists 不影响淘汰顺序
+func (s *store[K, V]) exists(key K) bool {
+	if s.closed.Load() {
+		return false
+	}
+	sh, _ := s.shardOf(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e, exists := sh.items[key]
+	return exists && !e.expired(time.Now().UnixNano())
+}
+
+// ttl 剩余TTL，不过期为0，不存在或已过期时返回false
+func (s *store[K, V]) ttl(key K) (time.Duration, bool) {
+	if s.closed.Load() {
+		return 0, false
+	}
+	sh, _ := s.shardOf(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	now := time.Now().UnixNano()
+	e, exists := sh.items[key]
+	if !exists || e.expired(now) {
+		return 0, false
+	}
+	if e.expireAt == 0 {
+		return 0, true
+	}
+	return time.Duration(e.expireAt - now), true
+}
+
+// clear
+func (s *store[K, V]) clear() error {
+	if s.closed.Load() {
+		return ErrCacheClosed
+	}
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for _, e := range sh.items {
+			sh.notify(e, EvictDeleted)
+		}
+		sh.reset()
+		sh.unlock()
+	}
+	return nil
+}
+
+// reset 需要持有分片的锁
+func (sh *shard[K, V]) reset() {
+	sh.items = make(map[K]*entry[K, V])
+	sh.tags = make(map[string]map[K]*entry[K, V])
+	sh.expiry = nil
+	sh.policy = newPolicy[K, V](sh.kind, sh.maxBytes, sh.entries)
+	sh.bytes = 0
+}
+
+// stats
+func (s *store[K, V]) stats() CacheStats {
+	stats := CacheStats{Errors: s.errors.Load()}
+	for _, sh := range s.shards {
+		stats.Hits += sh.hits.Load()
+		stats.Misses += sh.misses.Load()
+		stats.Sets += sh.sets.Load()
+		stats.Deletes += sh.deletes.Load()
+		stats.Evictions += sh.evictions.Load()
+		stats.Expirations += sh.expirations.Load()
+
+		sh.mu.Lock()
+		stats.TotalKeys += len(sh.items)
+		stats.MemoryUsage += sh.bytes
+		sh.mu.Unlock()
+	}
+	total := stats.Hits + stats.Misses
+	if total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+
+	return stats
+}
+
+// close
+func (s *store[K, V]) close() error {
+	if !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	close(s.stopCleanup)
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		sh.reset()
+		sh.mu.Unlock()
+	}
+
+	return nil
+}
+
+// cleaner 清理过期项目
+func (s *store[K, V]) cleaner() {
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanupExpired()
+		case <-s.stopCleanup:
+			return
+		}
+	}
+}
+
+// cleanupExpired 逐个分片从过期堆的堆顶清理过期项目
+func (s *store[K, V]) cleanupExpired() {
+	for _, sh := range s.shards {
+		if s.closed.Load() {
+			return
+		}
+		now := time.Now().UnixNano()
+		sh.mu.Lock()
+		for len(sh.expiry) > 0 && sh.expiry[0].expired(now) {
+			sh.removeEntry(sh.expiry[0], EvictExpired)
+			sh.expirations.Add(1)
+		}
+		sh.unlock()
+	}
+}
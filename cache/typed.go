@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"time"
+)
+
+// Typed 泛型内存缓存，直接存储值，不经过编码。
+// 值为指针、切片、map等引用类型时，读取到的值与缓存共享，需要隔离时设置CopyOnRead。
+type Typed[K comparable, V any] struct {
+	store   *store[K, V]
+	weigher func(K, V) int64
+	copy    func(V) V
+}
+
+// TypedConfig 泛型缓存配置
+type TypedConfig[K comparable, V any] struct {
+	MaxSize         int64            // 最大容量，按Weigher计算，默认10000
+	Weigher         func(K, V) int64 // 条目的权重，默认每个条目为1，即MaxSize为最大条目数
+	CopyOnRead      func(V) V        // 读取时复制值，默认直接返回缓存的值
+	CleanupInterval time.Duration    // 清理过期条目的间隔，默认1秒
+	Shards          int              // 分片数，默认16，向上取整为2的幂
+	Policy          EvictionPolicy   // 淘汰策略，默认PolicyLRU
+}
+
+// NewTyped 创建泛型缓存
+func NewTyped[K comparable, V any](config TypedConfig[K, V]) *Typed[K, V] {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 10000
+	}
+
+	seed := maphash.MakeSeed()
+	return &Typed[K, V]{
+		store: newStore[K, V](storeConfig{
+			maxSize:         config.MaxSize,
+			entrySize:       1,
+			cleanupInterval: config.CleanupInterval,
+			shards:          config.Shards,
+			policy:          config.Policy,
+		}, func(key K) uint64 {
+			return maphash.Comparable(seed, key)
+		}),
+		weigher: config.Weigher,
+		copy:    config.CopyOnRead,
+	}
+}
+
+// Get 获取缓存值，不存在或已过期时返回ErrKeyNotFound
+func (c *Typed[K, V]) Get(ctx context.Context, key K) (V, error) {
+	value, err := c.store.get(key)
+	if err != nil {
+		return value, err
+	}
+	if c.copy != nil {
+		value = c.copy(value)
+	}
+	return value, nil
+}
+
+// Set 设置缓存值，ttl为0时不过期，可以通过WithTags设置标签
+func (c *Typed[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration, opts ...SetOption) error {
+	size := int64(1)
+	if c.weigher != nil {
+		size = c.weigher(key, value)
+	}
+	return c.store.set(key, value, size, ttl, newSetOptions(opts).tags)
+}
+
+// OnEvict 设置条目被移除时的回调，value为移除前的值，nil表示取消。
+// 回调在释放分片的锁后同步调用，可以再访问缓存，但耗时的回调会阻塞触发移除的操作。
+func (c *Typed[K, V]) OnEvict(fn func(key K, value V, reason EvictReason)) {
+	c.store.onEvict(fn)
+}
+
+// InvalidateTags 删除带有任一标签的key
+func (c *Typed[K, V]) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.store.invalidateTags(tags)
+}
+
+// Delete 删除缓存值
+func (c *Typed[K, V]) Delete(ctx context.Context, key K) error {
+	return c.store.delete(key)
+}
+
+// Exists 检查key是否存在，不影响淘汰顺序
+func (c *Typed[K, V]) Exists(ctx context.Context, key K) bool {
+	return c.store.exists(key)
+}
+
+// Clear 清空缓存
+func (c *Typed[K, V]) Clear(ctx context.Context) error {
+	return c.store.clear()
+}
+
+// Stats 获取统计信息，MemoryUsage为Weigher计算的总权重
+func (c *Typed[K, V]) Stats() CacheStats {
+	return c.store.stats()
+}
+
+// Close 关闭缓存
+func (c *Typed[K, V]) Close() error {
+	return c.store.close()
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	ctx := context.Background()
+	c := NewTyped(TypedConfig[int, []int]{
+		MaxSize: 5,
+		Shards:  1,
+		Weigher: func(_ int, v []int) int64 { return int64(len(v)) },
+		CopyOnRead: func(v []int) []int {
+			return append([]int(nil), v...)
+		},
+	})
+	defer c.Close()
+
+	_ = c.Set(ctx, 1, []int{1, 2}, 0)
+	v, err := c.Get(ctx, 1)
+	if err != nil || len(v) != 2 {
+		t.Fatalf("get 1: %v %v", v, err)
+	}
+	v[0] = 100
+	if v, _ := c.Get(ctx, 1); v[0] != 1 {
+		t.Errorf("expect the cached value to be copied, got %v", v)
+	}
+
+	// 权重超过5时淘汰最久未访问的1
+	_ = c.Set(ctx, 2, []int{1, 2}, 0)
+	_ = c.Set(ctx, 3, []int{1, 2}, 0)
+	if c.Exists(ctx, 1) || !c.Exists(ctx, 2) || !c.Exists(ctx, 3) {
+		t.Error("expect 1 to be evicted")
+	}
+	if _, err := c.Get(ctx, 1); err != ErrKeyNotFound {
+		t.Errorf("expect not found, got %v", err)
+	}
+	if s := c.Stats(); s.TotalKeys != 2 || s.MemoryUsage != 4 || s.Evictions != 1 || s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestTypedStruct(t *testing.T) {
+	type key struct {
+		tenant string
+		id     int
+	}
+	ctx := context.Background()
+	c := NewTyped(TypedConfig[key, *testValue]{Policy: PolicyTinyLFU})
+	defer c.Close()
+
+	value := &testValue{"x"}
+	_ = c.Set(ctx, key{"a", 1}, value, 0)
+	if v, err := c.Get(ctx, key{"a", 1}); err != nil || v != value {
+		t.Errorf("expect the same pointer, got %v %v", v, err)
+	}
+	if c.Exists(ctx, key{"b", 1}) {
+		t.Error("unexpected key")
+	}
+	_ = c.Close()
+	if _, err := c.Get(ctx, key{"a", 1}); err != ErrCacheClosed {
+		t.Errorf("expect closed, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package ginny
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultStartTimeout the default timeout of starting a component
+	DefaultStartTimeout = 30 * time.Second
+	// DefaultStopTimeout the default timeout of stopping a component
+	DefaultStopTimeout = 30 * time.Second
+)
+
+// Component the component managed by the application, such as DB pools, consumers and caches.
+// Components are started in order before the servers, and stopped in reverse order after the servers.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Hook the lifecycle hook of the application
+type Hook func(ctx context.Context) error
+
+// ComponentOption the option of component
+type ComponentOption func(*component)
+
+// WithComponentName set the name of component for logs, default the type name.
+func WithComponentName(name string) ComponentOption {
+	return func(c *component) {
+		if name != "" {
+			c.name = name
+		}
+	}
+}
+
+// WithStartTimeout set the timeout of starting the component.
+func WithStartTimeout(d time.Duration) ComponentOption {
+	return func(c *component) {
+		if d > 0 {
+			c.startTimeout = d
+		}
+	}
+}
+
+// WithStopTimeout set the timeout of stopping the component.
+func WithStopTimeout(d time.Duration) ComponentOption {
+	return func(c *component) {
+		if d > 0 {
+			c.stopTimeout = d
+		}
+	}
+}
+
+// component the managed component
+type component struct {
+	Component
+	name         string
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+}
+
+// funcComponent
+type funcComponent struct {
+	start Hook
+	stop  Hook
+}
+
+// Start implement Component
+func (f *funcComponent) Start(ctx context.Context) error {
+	if f.start == nil {
+		return nil
+	}
+	return f.start(ctx)
+}
+
+// Stop implement Component
+func (f *funcComponent) Stop(ctx context.Context) error {
+	if f.stop == nil {
+		return nil
+	}
+	return f.stop(ctx)
+}
+
+// NewComponent new component with the start and stop funcs, any of them can be nil.
+func NewComponent(start, stop Hook) Component {
+	return &funcComponent{start: start, stop: stop}
+}
+
+// Use add the component to the application, must be called before Start.
+func (a *Application) Use(c Component, opts ...ComponentOption) *Application {
+	if c == nil {
+		return a
+	}
+	comp := &component{
+		Component:    c,
+		name:         fmt.Sprintf("%T", c),
+		startTimeout: DefaultStartTimeout,
+		stopTimeout:  DefaultStopTimeout,
+	}
+	for _, o := range opts {
+		o(comp)
+	}
+	a.components = append(a.components, comp)
+	return a
+}
+
+// OnStart add the hook called before the components start, the application fails to start if it returns error.
+func (a *Application) OnStart(h Hook) *Application {
+	if h != nil {
+		a.onStart = append(a.onStart, h)
+	}
+	return a
+}
+
+// OnReady add the hook called after the components started and the servers are listening.
+func (a *Application) OnReady(h Hook) *Application {
+	if h != nil {
+		a.onReady = append(a.onReady, h)
+	}
+	return a
+}
+
+// OnStop add the hook called after the servers closed, before the components stop.
+func (a *Application) OnStop(h Hook) *Application {
+	if h != nil {
+		a.onStop = append(a.onStop, h)
+	}
+	return a
+}
+
+// startComponents start the components in order, the started ones are stopped if any fails.
+func (a *Application) startComponents(ctx context.Context) error {
+	for i, c := range a.components {
+		a.Logger.Info("start component", zap.String("component", c.name))
+		err := runWithTimeout(ctx, c.startTimeout, c.Start)
+		if err == nil {
+			continue
+		}
+		a.Logger.Error("start component failed", zap.String("component", c.name), zap.Error(err))
+		a.stopComponents(ctx, a.components[:i])
+		return errors.Wrapf(err, "start component %s error", c.name)
+	}
+	return nil
+}
+
+// stopComponents stop the components in reverse order, return the first error.
+func (a *Application) stopComponents(ctx context.Context, components []*component) error {
+	var first error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		a.Logger.Info("stop component", zap.String("component", c.name))
+		if err := runWithTimeout(ctx, c.stopTimeout, c.Stop); err != nil {
+			a.Logger.Error("stop component failed", zap.String("component", c.name), zap.Error(err))
+			if first == nil {
+				first = errors.Wrapf(err, "stop component %s error", c.name)
+			}
+		}
+	}
+	return first
+}
+
+// runHooks run the hooks in order, stop at the first error if failFast.
+func (a *Application) runHooks(ctx context.Context, hooks []Hook, failFast bool) error {
+	var first error
+	for _, h := range hooks {
+		if err := h(ctx); err != nil {
+			if failFast {
+				return err
+			}
+			a.Logger.Error("run hook failed", zap.Error(err))
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// ready run the OnReady hooks when the servers are listening.
+func (a *Application) ready(ctx context.Context) {
+	select {
+	case <-a.Server.Ready():
+		_ = a.runHooks(ctx, a.onReady, false)
+	case <-ctx.Done():
+	}
+}
+
+// runWithTimeout run fn with timeout, return even if fn ignores the context.
+func runWithTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
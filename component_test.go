@@ -0,0 +1,86 @@
+package ginny
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/goriller/ginny/server"
+	"go.uber.org/zap"
+)
+
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) component(name string, startErr error) Component {
+	return NewComponent(func(ctx context.Context) error {
+		r.events = append(r.events, "start "+name)
+		return startErr
+	}, func(ctx context.Context) error {
+		r.events = append(r.events, "stop "+name)
+		return nil
+	})
+}
+
+func newTestApp() *Application {
+	return &Application{
+		Logger: zap.NewNop(),
+		Server: server.NewServer(context.Background(), zap.NewNop()),
+	}
+}
+
+func TestComponentsRollback(t *testing.T) {
+	r := &recorder{}
+	app := newTestApp()
+	app.Use(r.component("db", nil)).
+		Use(r.component("cache", nil)).
+		Use(r.component("consumer", errors.New("boom"))).
+		Use(r.component("never", nil))
+
+	if err := app.startComponents(context.Background()); err == nil {
+		t.Fatal("start should fail")
+	}
+	want := []string{"start db", "start cache", "start consumer", "stop cache", "stop db"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("expected %v, got %v", want, r.events)
+	}
+}
+
+func TestStopOrder(t *testing.T) {
+	r := &recorder{}
+	app := newTestApp()
+	app.Use(r.component("db", nil)).Use(r.component("cache", nil))
+	app.OnStop(func(ctx context.Context) error {
+		r.events = append(r.events, "on stop")
+		return nil
+	})
+	if err := app.startComponents(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start db", "start cache", "on stop", "stop cache", "stop db"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("expected %v, got %v", want, r.events)
+	}
+}
+
+func TestComponentTimeout(t *testing.T) {
+	app := newTestApp()
+	app.Use(NewComponent(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, nil), WithComponentName("slow"), WithStartTimeout(10*time.Millisecond))
+
+	err := app.startComponents(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -2,96 +2,202 @@ package config
 
 import (
 	"bytes"
-	"flag"
+	"context"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/google/wire"
+	"github.com/goriller/ginny-util/graceful"
 	"github.com/goriller/ginny/logger"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	_ "github.com/spf13/viper/remote"
+	"go.uber.org/zap"
 )
 
 var (
-	remoteConfig      string
-	defaultConfigPath string
-	// ConfigProviderSet
-	ConfigProviderSet = wire.NewSet(NewConfig)
+	// ConfigProviderSet the config from DefaultOptions
+	ConfigProviderSet = wire.NewSet(NewConfigWithOptions, DefaultOptions)
+	// ConfigWithOptionsProviderSet the config from the provided *Options
+	ConfigWithOptionsProviderSet = wire.NewSet(NewConfigWithOptions)
+
+	listenerMu sync.RWMutex
+	listeners  []*listener
 )
 
-func init() {
-	// 远程配置  etcd、consul
-	flag.StringVar(&remoteConfig, "remote", "", "remote config provider: etcd://127.0.0.1:8500/test or consul://127.0.0.1:6577/test ")
-	// 配置文件路径
-	flag.StringVar(&defaultConfigPath, "conf", "./configs/config.yaml", "uri to load config")
+// listener the registered change listener
+type listener struct {
+	fn func(v *viper.Viper)
 }
 
-// NewConfig
+// NewConfig new config with the DefaultOptions
 func NewConfig() (*viper.Viper, error) {
-	var (
-		err error
-		v   = viper.New()
-	)
-
-	flag.Parse()
+	return NewConfigWithOptions(DefaultOptions())
+}
 
-	v.AddConfigPath(".")
-	v.SetConfigFile(defaultConfigPath)
+// NewConfigWithOptions
+func NewConfigWithOptions(o *Options) (*viper.Viper, error) {
+	if o == nil {
+		o = NewOptions()
+	}
+	v := viper.New()
+	if len(o.Paths) > 0 {
+		v.SetConfigFile(o.Paths[0])
+	}
 
 	v.AutomaticEnv()
-	v.SetEnvPrefix("ginny")
+	v.SetEnvPrefix(o.EnvPrefix)
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	if err := loadConfig(v, o); err != nil {
+		return nil, err
+	}
 	// 监听配置文件变更
-	v.WatchConfig()
-	v.OnConfigChange(func(_ fsnotify.Event) {
-		log := logger.Default()
-		log.Info("Config file updated.")
-		err := loadConfig(v)
-		if err != nil {
-			log.Error("Config file reload error." + err.Error())
+	if o.Watch && o.Remote == "" {
+		if err := watchConfig(v, o); err != nil {
+			return nil, err
 		}
-	})
+	}
 
-	// if err := v.ReadInConfig(); err == nil {
-	// 	log.Printf("Config %s loaded successfully...", v.ConfigFileUsed())
-	// } else {
-	// 	return nil, err
-	// }
-	if err := loadConfig(v); err != nil {
-		return nil, err
+	return v, nil
+}
+
+// OnChange register the listener which is called after the config file reloaded,
+// the returned func removes the listener.
+func OnChange(fn func(v *viper.Viper)) (remove func()) {
+	if fn == nil {
+		return func() {}
 	}
+	l := &listener{fn: fn}
+	listenerMu.Lock()
+	defer listenerMu.Unlock()
+	listeners = append(listeners, l)
+	return func() {
+		listenerMu.Lock()
+		defer listenerMu.Unlock()
+		for i, cur := range listeners {
+			if cur == l {
+				listeners = append(listeners[:i:i], listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
 
-	return v, err
+// notify
+func notify(v *viper.Viper) {
+	listenerMu.RLock()
+	defer listenerMu.RUnlock()
+	for _, l := range listeners {
+		l.fn(v)
+	}
 }
 
 // loadConfig
-func loadConfig(v *viper.Viper) error {
+func loadConfig(v *viper.Viper, o *Options) error {
 	log := logger.Default()
 	log.Info("Loading config...")
 	// load config from remote
-	p := os.Getenv("REMOTE_CONFIG")
-	if remoteConfig == "" {
-		remoteConfig = p
-	}
-	if remoteConfig != "" {
-		return loadConfigFromRemote(v, remoteConfig)
+	if o.Remote != "" {
+		if err := loadConfigFromRemote(v, o.Remote); err != nil {
+			return err
+		}
+		return decryptConfig(v)
 	}
-	data, err := os.ReadFile(v.ConfigFileUsed())
-	if err != nil {
-		return err
+	if len(o.Paths) == 0 {
+		return errors.New("no config file")
 	}
 	log.Info("Getting environment variables...")
-	conf := expandEnv(string(data))
-	err = v.ReadConfig(bytes.NewReader([]byte(conf)))
+	for i, p := range o.Paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		conf := expandEnv(string(data))
+		v.SetConfigType(strings.TrimPrefix(filepath.Ext(p), "."))
+		if i == 0 {
+			err = v.ReadConfig(bytes.NewReader([]byte(conf)))
+		} else {
+			err = v.MergeConfig(bytes.NewReader([]byte(conf)))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "read config %s error", p)
+		}
+	}
+
+	return decryptConfig(v)
+}
+
+// watchConfig reload the config when any of the files changed,
+// the directories are watched to follow the symlink swap of k8s ConfigMap.
+func watchConfig(v *viper.Viper, o *Options) error {
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return err
 	}
+	files := make(map[string]string, len(o.Paths))
+	for _, p := range o.Paths {
+		f := filepath.Clean(p)
+		files[f], _ = filepath.EvalSymlinks(f)
+		if err := watcher.Add(filepath.Dir(f)); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+	graceful.AddCloser(func(_ context.Context) error {
+		return watcher.Close()
+	})
 
+	go func() {
+		log := logger.Default()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !changed(files, event) {
+					continue
+				}
+				log.Info("Config file updated.")
+				if err := loadConfig(v, o); err != nil {
+					log.Error("Config file reload error." + err.Error())
+					continue
+				}
+				notify(v)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error("Config watcher error.", zap.Error(err))
+			}
+		}
+	}()
 	return nil
 }
 
+// changed report whether the event changed any of the files, update the real path of them.
+func changed(files map[string]string, event fsnotify.Event) bool {
+	var updated bool
+	name := filepath.Clean(event.Name)
+	for f, real := range files {
+		current, _ := filepath.EvalSymlinks(f)
+		if current != "" && current != real {
+			files[f] = current
+			updated = true
+			continue
+		}
+		if name == f && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+			updated = true
+		}
+	}
+	return updated
+}
+
 // loadConfigFromRemote
 func loadConfigFromRemote(v *viper.Viper, uri string) error {
 	u, err := url.Parse(uri)
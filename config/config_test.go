@@ -0,0 +1,87 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	o := NewOptions()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-conf", "a.yaml,b.yaml", "-remote", "etcd://127.0.0.1:2379/test"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.Paths) != 2 || o.Paths[1] != "b.yaml" || o.Remote != "etcd://127.0.0.1:2379/test" {
+		t.Errorf("unexpected options %+v", o)
+	}
+	if flag.CommandLine.Lookup("conf") != nil {
+		t.Error("flags should not be registered to the command line by default")
+	}
+}
+
+func TestNewConfigWithOptions(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	local := filepath.Join(dir, "local.yaml")
+	writeFile(t, base, "app:\n  name: base\n  version: v1\n")
+	writeFile(t, local, "app:\n  name: local\n")
+
+	changes := make(chan string, 1)
+	remove := OnChange(func(v *viper.Viper) {
+		select {
+		case changes <- v.GetString("app.version"):
+		default:
+		}
+	})
+	defer remove()
+
+	v, err := NewConfigWithOptions(&Options{
+		Paths:     []string{base, local},
+		EnvPrefix: "ginny_test",
+		Watch:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GetString("app.name") != "local" || v.GetString("app.version") != "v1" {
+		t.Errorf("unexpected merged config %v", v.AllSettings())
+	}
+
+	writeFile(t, base, "app:\n  name: base\n  version: v2\n")
+	select {
+	case version := <-changes:
+		if version != "v2" {
+			t.Errorf("expected reloaded version v2, got %s", version)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("config should be reloaded after the file changed")
+	}
+}
+
+func TestOnChangeRemove(t *testing.T) {
+	var first, second int
+	removeFirst := OnChange(func(*viper.Viper) { first++ })
+	removeSecond := OnChange(func(*viper.Viper) { second++ })
+	defer removeSecond()
+
+	notify(viper.New())
+	removeFirst()
+	removeFirst()
+	notify(viper.New())
+	if first != 1 || second != 2 {
+		t.Errorf("first called %d times, second %d times", first, second)
+	}
+}
+
+func writeFile(t *testing.T, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
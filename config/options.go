@@ -0,0 +1,66 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+const defaultConfigPath = "./configs/config.yaml"
+
+// Options the options to load config.
+type Options struct {
+	// Paths the config files, the later one is merged into the former.
+	Paths []string
+	// Remote the remote config provider: etcd://127.0.0.1:8500/test or consul://127.0.0.1:6577/test?type=yaml,
+	// the files are ignored if set.
+	Remote string
+	// EnvPrefix the prefix of environment variables which override the config, e.g. GINNY_APP_NAME.
+	EnvPrefix string
+	// Watch reload the config files when changed.
+	Watch bool
+}
+
+var defaultOptions = NewOptions()
+
+// NewOptions the default options, the files from env CONFIG_PATH (comma separated)
+// and the remote from env REMOTE_CONFIG.
+func NewOptions() *Options {
+	o := &Options{
+		Paths:     []string{defaultConfigPath},
+		Remote:    os.Getenv("REMOTE_CONFIG"),
+		EnvPrefix: "ginny",
+		Watch:     true,
+	}
+	if p := os.Getenv("CONFIG_PATH"); p != "" {
+		o.Paths = strings.Split(p, ",")
+	}
+	return o
+}
+
+// DefaultOptions the options used by NewConfig, can be bound to command line flags by RegisterFlags.
+func DefaultOptions() *Options {
+	return defaultOptions
+}
+
+// RegisterFlags register the -conf and -remote flags to fs for the options,
+// must be called before fs parsed.
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	// 配置文件路径
+	fs.Func("conf", "config file path, comma separated for multiple files (default \""+
+		strings.Join(o.Paths, ",")+"\")", func(s string) error {
+		o.Paths = strings.Split(s, ",")
+		return nil
+	})
+	// 远程配置  etcd、consul
+	fs.StringVar(&o.Remote, "remote", o.Remote,
+		"remote config provider: etcd://127.0.0.1:8500/test or consul://127.0.0.1:6577/test ")
+}
+
+// RegisterFlags register the -conf and -remote flags to fs for the default options.
+//
+//	config.RegisterFlags(flag.CommandLine)
+//	flag.Parse()
+func RegisterFlags(fs *flag.FlagSet) {
+	defaultOptions.RegisterFlags(fs)
+}
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/goriller/ginny/logger"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	encPrefix = "ENC("
+	encSuffix = ")"
+	// KeySize the AES-256 key size
+	KeySize = 32
+	// redacted the mask of secrets in config dumps
+	redacted = "******"
+)
+
+var (
+	// ErrNoKey no key provided for the encrypted values
+	ErrNoKey = errors.New("config: no key for encrypted values")
+
+	keyProvider KeyProvider = ChainKeyProvider{
+		EnvKeyProvider("CONFIG_KEY"),
+		FileKeyProvider(os.Getenv("CONFIG_KEY_FILE")),
+	}
+
+	secretMu sync.RWMutex
+	// masks the redacted copies of the decrypted values by config, keyed by the setting path
+	masks = map[*viper.Viper]map[string]interface{}{}
+)
+
+// KeyProvider provide the key to decrypt the `ENC(...)` values in config.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyProviderFunc the func implement KeyProvider.
+type KeyProviderFunc func() ([]byte, error)
+
+// Key implement KeyProvider
+func (f KeyProviderFunc) Key() ([]byte, error) {
+	return f()
+}
+
+// EnvKeyProvider read the base64 encoded key from the environment variable.
+type EnvKeyProvider string
+
+// Key implement KeyProvider
+func (e EnvKeyProvider) Key() ([]byte, error) {
+	v := os.Getenv(string(e))
+	if v == "" {
+		return nil, ErrNoKey
+	}
+	return decodeKey(v)
+}
+
+// FileKeyProvider read the base64 encoded key from the local file.
+type FileKeyProvider string
+
+// Key implement KeyProvider
+func (f FileKeyProvider) Key() ([]byte, error) {
+	if f == "" {
+		return nil, ErrNoKey
+	}
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return nil, errors.Wrap(err, "read key file error")
+	}
+	return decodeKey(strings.TrimSpace(string(data)))
+}
+
+// ChainKeyProvider try the providers in order, return the first key found.
+type ChainKeyProvider []KeyProvider
+
+// Key implement KeyProvider
+func (c ChainKeyProvider) Key() ([]byte, error) {
+	for _, p := range c {
+		key, err := p.Key()
+		if errors.Is(err, ErrNoKey) {
+			continue
+		}
+		return key, err
+	}
+	return nil, ErrNoKey
+}
+
+// SetKeyProvider set the key provider, default read from env CONFIG_KEY then the file of env CONFIG_KEY_FILE.
+func SetKeyProvider(p KeyProvider) {
+	if p != nil {
+		keyProvider = p
+	}
+}
+
+// GenerateKey generate a random base64 encoded key.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt encrypt the value with the base64 encoded key, return `ENC(...)` which can be put in config file.
+//
+//	key, _ := config.GenerateKey()
+//	enc, _ := config.Encrypt(key, "db-password")
+func Encrypt(key, value string) (string, error) {
+	k, err := decodeKey(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(data) + encSuffix, nil
+}
+
+// Decrypt decrypt the `ENC(...)` value with the key.
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value[len(encPrefix) : len(value)-len(encSuffix)])
+	if err != nil {
+		return "", errors.Wrap(err, "decode encrypted value error")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypt value error")
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted report whether the value is `ENC(...)`.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) && strings.HasSuffix(value, encSuffix)
+}
+
+// Dump return a copy of all the settings with the decrypted values redacted.
+func Dump(v *viper.Viper) map[string]interface{} {
+	settings, _ := copyValue(v.AllSettings()).(map[string]interface{})
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	for k, mask := range masks[v] {
+		setPath(settings, strings.Split(k, "."), copyValue(mask))
+	}
+	return settings
+}
+
+// decryptConfig decrypt all the `ENC(...)` string values, including the strings in lists,
+// the decrypted values are redacted in logs and dumps.
+// The decrypted values are merged into the config layer which is replaced by every load,
+// rather than the override layer which would shadow the reloaded values.
+func decryptConfig(v *viper.Viper) error {
+	d := &decrypter{}
+	decrypted := map[string]interface{}{}
+	masked := map[string]interface{}{}
+	for _, k := range v.AllKeys() {
+		value, mask, changed, err := d.decrypt(v.Get(k))
+		if err != nil {
+			return errors.Wrapf(err, "decrypt config %s error", k)
+		}
+		if changed {
+			setPath(decrypted, strings.Split(k, "."), value)
+			masked[k] = mask
+		}
+	}
+
+	// the secrets of the previous load are dropped
+	secretMu.Lock()
+	masks[v] = masked
+	secretMu.Unlock()
+	logger.RedactGroup("config", d.secrets...)
+	if len(decrypted) == 0 {
+		return nil
+	}
+	return v.MergeConfigMap(decrypted)
+}
+
+// decrypter get the key on the first encrypted value
+type decrypter struct {
+	key     []byte
+	secrets []string
+}
+
+// decrypt return the decrypted copy of the value, the copy with the decrypted strings redacted
+// and whether any string in it is decrypted
+func (d *decrypter) decrypt(value interface{}) (interface{}, interface{}, bool, error) {
+	switch val := value.(type) {
+	case string:
+		if !IsEncrypted(val) {
+			return val, val, false, nil
+		}
+		if d.key == nil {
+			key, err := keyProvider.Key()
+			if err != nil {
+				return nil, nil, false, errors.Wrap(err, "get config key error")
+			}
+			d.key = key
+		}
+		plain, err := Decrypt(d.key, val)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		d.secrets = append(d.secrets, plain)
+		return plain, redacted, true, nil
+	case []interface{}:
+		var changed bool
+		list := make([]interface{}, len(val))
+		mask := make([]interface{}, len(val))
+		for i := range val {
+			item, m, ok, err := d.decrypt(val[i])
+			if err != nil {
+				return nil, nil, false, err
+			}
+			list[i], mask[i], changed = item, m, changed || ok
+		}
+		return list, mask, changed, nil
+	case map[string]interface{}:
+		var changed bool
+		m := make(map[string]interface{}, len(val))
+		mask := make(map[string]interface{}, len(val))
+		for k := range val {
+			item, mk, ok, err := d.decrypt(val[k])
+			if err != nil {
+				return nil, nil, false, err
+			}
+			m[k], mask[k], changed = item, mk, changed || ok
+		}
+		return m, mask, changed, nil
+	default:
+		return value, value, false, nil
+	}
+}
+
+// setPath set the value of the nested key path in m
+func setPath(m map[string]interface{}, path []string, value interface{}) {
+	for _, k := range path[:len(path)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
+
+// copyValue deep copy the maps and lists, the settings of viper share them with the config
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k := range val {
+			m[k] = copyValue(val[k])
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i := range val {
+			list[i] = copyValue(val[i])
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// decodeKey
+func decodeKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode key error")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key size %d, must be %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// newGCM
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goriller/ginny/logger"
+	"github.com/spf13/viper"
+)
+
+func TestEncryptedConfig(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_KEY", key)
+
+	enc, err := Encrypt(key, "s3cret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(enc) {
+		t.Fatalf("unexpected encrypted value %s", enc)
+	}
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := "db:\n  user: root\n  password: " + enc + "\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	if err := loadConfig(v, &Options{Paths: []string{file}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("db.password"); got != "s3cret-password" {
+		t.Errorf("expected decrypted password, got %s", got)
+	}
+
+	dump := Dump(v)
+	db := dump["db"].(map[string]interface{})
+	if db["password"] != redacted || db["user"] != "root" {
+		t.Errorf("unexpected dump %v", dump)
+	}
+	if out := string(logger.RedactBytes([]byte(`{"dsn":"root:s3cret-password@tcp"}`))); strings.Contains(out, "s3cret") {
+		t.Errorf("secret should be redacted in logs, got %s", out)
+	}
+}
+
+func TestEncryptedConfigReload(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_KEY", key)
+	encrypt := func(s string) string {
+		enc, err := Encrypt(key, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return enc
+	}
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	o := &Options{Paths: []string{file}}
+	data := "db:\n  password: " + encrypt("old") + "\n  replica: " + encrypt("replica") +
+		"\ntokens:\n  - plain\n  - " + encrypt("token") + "\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	if err := loadConfig(v, o); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetStringSlice("tokens"); len(got) != 2 || got[0] != "plain" || got[1] != "token" {
+		t.Errorf("expected decrypted list, got %v", got)
+	}
+
+	// dumping must not change the config
+	dump := Dump(v)
+	if tokens := dump["tokens"].([]interface{}); tokens[0] != "plain" || tokens[1] != redacted {
+		t.Errorf("unexpected dump %v", dump)
+	}
+	if db := dump["db"].(map[string]interface{}); db["password"] != redacted || db["replica"] != redacted {
+		t.Errorf("unexpected dump %v", dump)
+	}
+	if got := v.GetStringSlice("tokens"); len(got) != 2 || got[1] != "token" {
+		t.Errorf("the config is changed by dump, got %v", got)
+	}
+	if got := v.GetString("db.password"); got != "old" {
+		t.Errorf("the config is changed by dump, got %s", got)
+	}
+
+	// rotate the secret and remove a key
+	data = "db:\n  password: " + encrypt("new") + "\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(v, o); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("db.password"); got != "new" {
+		t.Errorf("expected the rotated password, got %s", got)
+	}
+	if v.IsSet("db.replica") || v.IsSet("tokens") {
+		t.Errorf("removed keys are still set: %v", v.AllSettings())
+	}
+	if dump := Dump(v); dump["db"].(map[string]interface{})["password"] != redacted {
+		t.Errorf("unexpected dump %v", dump)
+	}
+	out := string(logger.RedactBytes([]byte("replica-host new-password")))
+	if out != "replica-host new-password" {
+		t.Errorf("the secrets of the previous load should be dropped, got %s", out)
+	}
+}
+
+func TestDecryptWithWrongKey(t *testing.T) {
+	key, _ := GenerateKey()
+	other, _ := GenerateKey()
+	enc, err := Encrypt(key, "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, _ := decodeKey(other)
+	if _, err := Decrypt(k, enc); err == nil {
+		t.Error("decrypt with wrong key should fail")
+	}
+	if _, err := (ChainKeyProvider{EnvKeyProvider("NOT_EXISTS_KEY"), FileKeyProvider("")}).Key(); err != ErrNoKey {
+		t.Errorf("expected ErrNoKey, got %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+// Package flags provide feature flags backed by the `flags` section of config.
+//
+//	flags:
+//	  new-checkout:               # boolean flag with 30% rollout by device id
+//	    enabled: true
+//	    percentage: 30
+//	    bucketBy: device_id
+//	    rules:
+//	      - key: x-tenant-id      # context tag or incoming metadata
+//	        values: ["acme"]
+//	        enabled: true
+//	  homepage:                   # variant flag
+//	    enabled: true
+//	    variants:
+//	      - name: control
+//	        weight: 50
+//	      - name: blue
+//	        weight: 50
+//	    rules:
+//	      - key: x-tenant-id
+//	        values: ["acme"]
+//	        variant: blue
+//
+// The requests are bucketed only by stable ids, a request without any of the ids is out of
+// the percentage rollout and gets the first variant.
+// The flag names are case-insensitive, the flags are reloaded when the config file changed.
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/wire"
+	"github.com/goriller/ginny/config"
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/goriller/ginny/logger"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	configKey = "flags"
+	// buckets the precision of percentage is 0.01%
+	buckets = 10000
+	// unknownFlag the metric label of the undefined flags
+	unknownFlag = "unknown"
+)
+
+var (
+	// FlagsProviderSet
+	FlagsProviderSet = wire.NewSet(New)
+
+	// DefaultBucketBy the keys used to bucket the percentage and variants by default, they must be
+	// stable for the same user, a per request id flips the result between requests.
+	DefaultBucketBy = []string{"device_id"}
+
+	evaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ginny_flag_evaluations_total",
+		Help: "Total number of feature flag evaluations.",
+	}, []string{"flag", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(evaluations)
+}
+
+// Flag the feature flag definition.
+type Flag struct {
+	// Enabled the master switch of the flag, a disabled flag is always off and returns no variant.
+	Enabled bool
+	// Percentage rollout percentage (0-100) of enabled requests, nil means 100.
+	Percentage *float64
+	// BucketBy the context keys used to bucket the request, default DefaultBucketBy.
+	BucketBy []string
+	// Variants weighted variants of the flag.
+	Variants []Variant
+	// Rules the targeting rules, the first matched rule decides the result.
+	Rules []Rule
+}
+
+// Variant the weighted variant.
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+// Rule the targeting rule, matched when the value of Key in context is one of Values.
+type Rule struct {
+	// Key the context tag key (device_id, request_id ...) or incoming metadata key (x-tenant-id ...).
+	Key    string
+	Values []string
+	// Enabled the result when the rule matched, nil keeps the result of the flag.
+	Enabled *bool
+	// Variant the variant when the rule matched, empty keeps the variant of the flag.
+	Variant string
+}
+
+// Result the evaluation result.
+type Result struct {
+	Enabled bool
+	Variant string
+}
+
+// Flags the feature flags.
+type Flags struct {
+	mu        sync.RWMutex
+	flags     map[string]*Flag
+	overrides map[string]Result
+	// stop remove the config change listener
+	stop func()
+}
+
+// New create flags from the `flags` section of config, and reload them on config change
+// until Close. The flags provided by FlagsProviderSet live as long as the process.
+func New(v *viper.Viper) (*Flags, error) {
+	f := NewWithFlags(nil)
+	if err := f.load(v); err != nil {
+		return nil, err
+	}
+	f.stop = config.OnChange(func(v *viper.Viper) {
+		if err := f.load(v); err != nil {
+			logger.Default().Error("Flags reload error.", zap.Error(err))
+		}
+	})
+	return f, nil
+}
+
+// Close stop reloading the flags on config change.
+func (f *Flags) Close() {
+	if f.stop != nil {
+		f.stop()
+	}
+}
+
+// NewWithFlags create flags with the static definitions.
+func NewWithFlags(flags map[string]Flag) *Flags {
+	f := &Flags{
+		overrides: map[string]Result{},
+	}
+	f.Set(flags)
+	return f
+}
+
+// Set replace all the flag definitions.
+func (f *Flags) Set(flags map[string]Flag) {
+	m := make(map[string]*Flag, len(flags))
+	for name, flag := range flags {
+		flag := flag
+		m[strings.ToLower(name)] = &flag
+	}
+	f.mu.Lock()
+	f.flags = m
+	f.mu.Unlock()
+}
+
+// load
+func (f *Flags) load(v *viper.Viper) error {
+	flags := map[string]Flag{}
+	if err := v.UnmarshalKey(configKey, &flags); err != nil {
+		return errors.Wrap(err, "unmarshal flags error")
+	}
+	f.Set(flags)
+	return nil
+}
+
+// Override force the result of the flag, the returned func restores it.
+func (f *Flags) Override(name string, result Result) (restore func()) {
+	name = strings.ToLower(name)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prev, ok := f.overrides[name]
+	f.overrides[name] = result
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if ok {
+			f.overrides[name] = prev
+		} else {
+			delete(f.overrides, name)
+		}
+	}
+}
+
+// Enabled report whether the flag is on for the request.
+func (f *Flags) Enabled(ctx context.Context, name string) bool {
+	return f.Evaluate(ctx, name).Enabled
+}
+
+// Variant return the variant of the flag for the request, empty if the flag is off.
+func (f *Flags) Variant(ctx context.Context, name string) string {
+	return f.Evaluate(ctx, name).Variant
+}
+
+// Evaluate evaluate the flag against the request context.
+func (f *Flags) Evaluate(ctx context.Context, name string) Result {
+	name = strings.ToLower(name)
+	f.mu.RLock()
+	res, ok := f.overrides[name]
+	flag := f.flags[name]
+	f.mu.RUnlock()
+	if !ok && flag != nil {
+		res = flag.evaluate(ctx, name)
+	}
+
+	result := res.Variant
+	if result == "" {
+		result = strconv.FormatBool(res.Enabled)
+	}
+	if !ok && flag == nil {
+		// the name is not defined, keep the cardinality of the label bounded
+		name = unknownFlag
+	}
+	evaluations.WithLabelValues(name, result).Inc()
+	return res
+}
+
+// evaluate
+func (fl *Flag) evaluate(ctx context.Context, name string) Result {
+	if !fl.Enabled {
+		return Result{}
+	}
+	res := Result{Enabled: true}
+	bucket, bucketed := -1, false
+	if p := fl.Percentage; p != nil && *p < 100 {
+		bucket, bucketed = fl.bucket(ctx, name)
+		res.Enabled = bucketed && float64(bucket) < *p*buckets/100
+	}
+
+	for _, r := range fl.Rules {
+		if !r.match(ctx) {
+			continue
+		}
+		if r.Enabled != nil {
+			res.Enabled = *r.Enabled
+		}
+		if res.Enabled && r.Variant != "" {
+			res.Variant = r.Variant
+			return res
+		}
+		break
+	}
+
+	if res.Enabled && len(fl.Variants) > 0 {
+		if bucket < 0 {
+			bucket, bucketed = fl.bucket(ctx, name)
+		}
+		if !bucketed {
+			res.Variant = fl.Variants[0].Name
+			return res
+		}
+		res.Variant = fl.variant(bucket)
+	}
+	return res
+}
+
+// bucket hash the bucket value of the request into [0, buckets), false if the request has none.
+func (fl *Flag) bucket(ctx context.Context, name string) (int, bool) {
+	keys := fl.BucketBy
+	if len(keys) == 0 {
+		keys = DefaultBucketBy
+	}
+	var value string
+	for _, k := range keys {
+		if value = lookup(ctx, k); value != "" {
+			break
+		}
+	}
+	if value == "" {
+		return 0, false
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + value))
+	return int(h.Sum32() % buckets), true
+}
+
+// variant pick the variant by weight.
+func (fl *Flag) variant(bucket int) string {
+	var total int
+	for _, v := range fl.Variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		return fl.Variants[0].Name
+	}
+	n := bucket * total / buckets
+	for _, v := range fl.Variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	return fl.Variants[len(fl.Variants)-1].Name
+}
+
+// match
+func (r *Rule) match(ctx context.Context) bool {
+	value := lookup(ctx, r.Key)
+	if value == "" {
+		return false
+	}
+	for _, v := range r.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup the value of key from context tags, then the incoming metadata.
+func lookup(ctx context.Context, key string) string {
+	if key == "" {
+		return ""
+	}
+	if v, ok := tags.Extract(ctx).Values()[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprintf("%v", v)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(key); len(vals) > 0 {
+			return vals[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package flags_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/goriller/ginny/flags"
+	"github.com/goriller/ginny/flags/flagstest"
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
+)
+
+const testConfig = `
+flags:
+  New-Checkout:
+    enabled: true
+    percentage: 30
+    rules:
+      - key: x-tenant-id
+        values: ["acme"]
+        enabled: true
+  homepage:
+    enabled: true
+    variants:
+      - name: control
+        weight: 50
+      - name: blue
+        weight: 50
+    rules:
+      - key: x-tenant-id
+        values: ["acme"]
+        variant: blue
+  disabled:
+    enabled: false
+  dark-launch:
+    enabled: true
+    percentage: 0
+`
+
+func newFlags(t *testing.T) *flags.Flags {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewBufferString(testConfig)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := flags.New(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(f.Close)
+	return f
+}
+
+func deviceContext(id string) context.Context {
+	ts := tags.NewTags()
+	ts.Set("device_id", id)
+	return tags.InjectIntoContext(context.Background(), ts)
+}
+
+func TestPercentage(t *testing.T) {
+	f := newFlags(t)
+	var enabled int
+	for i := 0; i < 1000; i++ {
+		ctx := deviceContext(fmt.Sprintf("device-%d", i))
+		on := f.Enabled(ctx, "new-checkout")
+		if on != f.Enabled(ctx, "NEW-CHECKOUT") {
+			t.Fatal("evaluation should be sticky for the same device")
+		}
+		if on {
+			enabled++
+		}
+	}
+	if enabled < 200 || enabled > 400 {
+		t.Errorf("expected about 30%% enabled, got %d/1000", enabled)
+	}
+	if f.Enabled(context.Background(), "disabled") || f.Enabled(context.Background(), "unknown") {
+		t.Error("disabled and unknown flags should be off")
+	}
+	for i := 0; i < 100; i++ {
+		if f.Enabled(deviceContext(fmt.Sprintf("device-%d", i)), "dark-launch") {
+			t.Fatal("0% rollout should be off")
+		}
+	}
+}
+
+func TestWithoutBucketID(t *testing.T) {
+	f := newFlags(t)
+	ts := tags.NewTags()
+	ts.Set("request_id", "req-1")
+	for _, ctx := range []context.Context{context.Background(), tags.InjectIntoContext(context.Background(), ts)} {
+		if f.Enabled(ctx, "new-checkout") {
+			t.Error("the request without a stable id should be out of the rollout")
+		}
+		if v := f.Variant(ctx, "homepage"); v != "control" {
+			t.Errorf("the request without a stable id should get the first variant, got %q", v)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	f := newFlags(t)
+	ctx := metadata.NewIncomingContext(deviceContext("device-1"),
+		metadata.Pairs("x-tenant-id", "acme"))
+	if !f.Enabled(ctx, "new-checkout") {
+		t.Error("tenant acme should be enabled by rule")
+	}
+	if v := f.Variant(ctx, "homepage"); v != "blue" {
+		t.Errorf("tenant acme should get variant blue, got %q", v)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		seen[f.Variant(deviceContext(fmt.Sprintf("device-%d", i)), "homepage")] = true
+	}
+	if !seen["control"] || !seen["blue"] || len(seen) != 2 {
+		t.Errorf("unexpected variants %v", seen)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	f := newFlags(t)
+	t.Run("override", func(t *testing.T) {
+		flagstest.Enable(t, f, "disabled", true)
+		flagstest.Variant(t, f, "homepage", "red")
+		if !f.Enabled(context.Background(), "disabled") {
+			t.Error("override should enable the flag")
+		}
+		if v := f.Variant(context.Background(), "homepage"); v != "red" {
+			t.Errorf("override variant should be red, got %q", v)
+		}
+	})
+	if f.Enabled(context.Background(), "disabled") {
+		t.Error("override should be restored after the test")
+	}
+}
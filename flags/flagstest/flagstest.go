@@ -0,0 +1,26 @@
+// Package flagstest provide helpers to override feature flags in tests.
+package flagstest
+
+import (
+	"testing"
+
+	"github.com/goriller/ginny/flags"
+)
+
+// New create flags with the static definitions for test.
+func New(tb testing.TB, defs map[string]flags.Flag) *flags.Flags {
+	tb.Helper()
+	return flags.NewWithFlags(defs)
+}
+
+// Enable force the flag on or off until the test finished.
+func Enable(tb testing.TB, f *flags.Flags, name string, enabled bool) {
+	tb.Helper()
+	tb.Cleanup(f.Override(name, flags.Result{Enabled: enabled}))
+}
+
+// Variant force the flag on with the variant until the test finished.
+func Variant(tb testing.TB, f *flags.Flags, name, variant string) {
+	tb.Helper()
+	tb.Cleanup(f.Override(name, flags.Result{Enabled: true, Variant: variant}))
+}
@@ -0,0 +1,28 @@
+package flags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEvaluationsUnknownFlag(t *testing.T) {
+	f := NewWithFlags(map[string]Flag{"known": {Enabled: true}})
+	unknown := testutil.ToFloat64(evaluations.WithLabelValues(unknownFlag, "false"))
+	known := testutil.ToFloat64(evaluations.WithLabelValues("known", "true"))
+
+	f.Enabled(context.Background(), "known")
+	f.Enabled(context.Background(), "missing-1")
+	f.Enabled(context.Background(), "missing-2")
+
+	if n := testutil.ToFloat64(evaluations.WithLabelValues(unknownFlag, "false")) - unknown; n != 2 {
+		t.Errorf("unknown evaluations = %v, want 2", n)
+	}
+	if n := testutil.ToFloat64(evaluations.WithLabelValues("known", "true")) - known; n != 1 {
+		t.Errorf("known evaluations = %v, want 1", n)
+	}
+	if evaluations.DeleteLabelValues("missing-1", "false") {
+		t.Error("undefined flag name should not be a label value")
+	}
+}
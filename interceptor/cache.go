@@ -0,0 +1,132 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/goriller/ginny/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// CacheBypassHeader the default header to skip reading the cached response,
+	// the fresh response is still cached.
+	CacheBypassHeader = "x-cache-bypass"
+	// CacheStatusHeader the response header tells whether the response is served from the cache.
+	CacheStatusHeader = "x-cache"
+)
+
+// CacheRule caching rule of a read-only method.
+type CacheRule struct {
+	// TTL how long the response is cached.
+	TTL time.Duration
+	// Metadata the request metadata (e.g. tenant) that is part of the key,
+	// responses of requests with different values are cached separately.
+	Metadata []string
+}
+
+// ResponseCache caches the responses of the configured read-only methods.
+//
+// The gRPC server keys responses by the full method, the deterministic proto serialization
+// of the request and the values of the rule's metadata, the gateway by the URL and the
+// metadata headers of all rules. Responses are tagged with the full method, so
+// Cache.InvalidateTags(method) invalidates the cached responses of a method.
+type ResponseCache struct {
+	// Cache stores the responses, the codec must be able to encode []byte and structs.
+	Cache cache.Cache
+	// Methods the cached methods by gRPC full method, e.g. "/pkg.Service/GetItem".
+	Methods map[string]CacheRule
+	// BypassHeader the request header to bypass the cache, default CacheBypassHeader.
+	BypassHeader string
+}
+
+// Rule the caching rule of the method.
+func (rc *ResponseCache) Rule(method string) (CacheRule, bool) {
+	rule, ok := rc.Methods[method]
+	return rule, ok && rule.TTL > 0
+}
+
+// Bypass the header name to bypass the cache.
+func (rc *ResponseCache) Bypass() string {
+	if rc.BypassHeader == "" {
+		return CacheBypassHeader
+	}
+	return rc.BypassHeader
+}
+
+// CacheUnaryServerInterceptor returns a new unary server interceptor that serves the responses
+// of the configured methods from the cache. Only successful responses are cached.
+func CacheUnaryServerInterceptor(rc *ResponseCache) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		rule, ok := rc.Rule(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := make([]string, len(rule.Metadata))
+		for i, k := range rule.Metadata {
+			if v := md.Get(k); len(v) > 0 {
+				values[i] = v[0]
+			}
+		}
+		key := "grpc:" + CacheKey(info.FullMethod, body, values)
+
+		if len(md.Get(rc.Bypass())) == 0 {
+			var data []byte
+			if err := rc.Cache.Get(ctx, key, &data); err == nil {
+				var a anypb.Any
+				if err := proto.Unmarshal(data, &a); err == nil {
+					if resp, err := a.UnmarshalNew(); err == nil {
+						_ = grpc.SetHeader(ctx, metadata.Pairs(CacheStatusHeader, "HIT"))
+						return resp, nil
+					}
+				}
+			}
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(CacheStatusHeader, "MISS"))
+		if m, ok := resp.(proto.Message); ok {
+			if a, err := anypb.New(m); err == nil {
+				if data, err := proto.Marshal(a); err == nil {
+					// the response is returned even if it can not be cached
+					_ = rc.Cache.Set(ctx, key, data, rule.TTL, cache.WithTags(info.FullMethod))
+				}
+			}
+		}
+		return resp, nil
+	}
+}
+
+// CacheKey the key of a cached response: the method followed by the hash of the request
+// and the metadata values.
+func CacheKey(method string, req []byte, values []string) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write(req)
+	for _, v := range values {
+		h.Write([]byte{0})
+		h.Write([]byte(v))
+	}
+	return method + ":" + hex.EncodeToString(h.Sum(nil))
+}
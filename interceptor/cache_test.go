@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goriller/ginny/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCacheUnaryServerInterceptor(t *testing.T) {
+	c := cache.NewMemoryCache(cache.MemoryCacheConfig{})
+	defer c.Close()
+	rc := &ResponseCache{
+		Cache: c,
+		Methods: map[string]CacheRule{
+			"/pkg.Service/Get": {TTL: time.Minute, Metadata: []string{"x-tenant"}},
+		},
+	}
+	ic := CacheUnaryServerInterceptor(rc)
+
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if req.(*wrapperspb.StringValue).Value == "missing" {
+			return nil, status.Error(codes.NotFound, "not found")
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		return wrapperspb.String(req.(*wrapperspb.StringValue).Value + md.Get("x-tenant")[0]), nil
+	}
+	call := func(method, value string, md ...string) (interface{}, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(md...))
+		return ic(ctx, wrapperspb.String(value), &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	first, err := call("/pkg.Service/Get", "id", "x-tenant", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := call("/pkg.Service/Get", "id", "x-tenant", "a", "x-request-id", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || !proto.Equal(first.(proto.Message), second.(proto.Message)) {
+		t.Fatalf("expect a cached response, calls = %d, got %v", calls, second)
+	}
+
+	// other tenants, requests and methods, the bypass header and errors reach the handler
+	if resp, _ := call("/pkg.Service/Get", "id", "x-tenant", "b"); resp.(*wrapperspb.StringValue).Value != "idb" {
+		t.Errorf("tenant b got %v", resp)
+	}
+	_, _ = call("/pkg.Service/Get", "other", "x-tenant", "a")
+	_, _ = call("/pkg.Service/List", "id", "x-tenant", "a")
+	_, _ = call("/pkg.Service/List", "id", "x-tenant", "a")
+	_, _ = call("/pkg.Service/Get", "id", "x-tenant", "a", CacheBypassHeader, "1")
+	for i := 0; i < 2; i++ {
+		if _, err := call("/pkg.Service/Get", "missing", "x-tenant", "a"); status.Code(err) != codes.NotFound {
+			t.Errorf("expect NotFound, got %v", err)
+		}
+	}
+	if calls != 8 {
+		t.Errorf("calls = %d, want 8", calls)
+	}
+
+	if err := c.InvalidateTags(context.Background(), "/pkg.Service/Get"); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = call("/pkg.Service/Get", "id", "x-tenant", "a")
+	if calls != 9 {
+		t.Errorf("invalidated response served, calls = %d", calls)
+	}
+}
@@ -0,0 +1,43 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/goriller/ginny/logger"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// LoggerUnaryServerInterceptor store the request scoped logger with the method, tags and trace ids
+// in the context, the handlers get it by logger.FromContext. It should be chained after the tracer.
+func LoggerUnaryServerInterceptor(l *zap.Logger) grpc.UnaryServerInterceptor {
+	if l == nil {
+		l = logger.Default()
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(contextWithLogger(ctx, l, info.FullMethod), req)
+	}
+}
+
+// LoggerStreamServerInterceptor store the request scoped logger in the context of stream.
+func LoggerStreamServerInterceptor(l *zap.Logger) grpc.StreamServerInterceptor {
+	if l == nil {
+		l = logger.Default()
+	}
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = contextWithLogger(stream.Context(), l, info.FullMethod)
+		return handler(srv, wrapped)
+	}
+}
+
+// contextWithLogger
+func contextWithLogger(ctx context.Context, l *zap.Logger, method string) context.Context {
+	fields := append(logger.ContextFields(ctx), zap.String("action", method))
+	return logger.SetContextLogger(ctx, l.With(fields...))
+}
@@ -7,13 +7,13 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/goriller/ginny/interceptor/tags"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -32,6 +32,19 @@ type reporter struct {
 	typ                      interceptors.GRPCType
 	startCallLogged          bool
 	hasLoggingRequestContent bool
+	start                    time.Time
+	stream                   *streamStats
+}
+
+// streamStats the message stats of streaming RPC
+type streamStats struct {
+	recvCount atomic.Int64
+	sendCount atomic.Int64
+	recvBytes atomic.Int64
+	sendBytes atomic.Int64
+	// the time to the first message since the call started
+	firstRecv atomic.Int64
+	firstSend atomic.Int64
 }
 
 func (r *reportable) reporter(ctx context.Context, typ interceptors.GRPCType,
@@ -39,7 +52,13 @@ func (r *reportable) reporter(ctx context.Context, typ interceptors.GRPCType,
 ) (interceptors.Reporter, context.Context) {
 	fields := commonFields(ctx, kind, service, method, typ)
 	ctx = logging.InjectFields(ctx, fields)
+	var stream *streamStats
+	if typ != interceptors.Unary {
+		stream = &streamStats{}
+	}
 	return &reporter{
+		start:           time.Now(),
+		stream:          stream,
 		ctx:             ctx,
 		typ:             typ,
 		service:         service,
@@ -62,11 +81,15 @@ func commonFields(ctx context.Context, kind, service, method string, typ interce
 
 // PostCall implement
 func (c *reporter) PostCall(err error, duration time.Duration) {
-	switch shouldLog(c.opts.shouldLog, FullMethod(c.service, c.method), err).Events {
+	payloadDecision := shouldLog(c.opts.shouldLog, FullMethod(c.service, c.method), err)
+	switch payloadDecision.Events {
 	case logging.FinishCall:
 		if errors.Is(err, io.EOF) {
 			err = nil
 		}
+		if c.stream != nil && payloadDecision.Stream {
+			c.ctx = logging.InjectFields(c.ctx, c.stream.fields())
+		}
 		c.logMessage(c.logger, err, "finished call", duration)
 	default:
 		return
@@ -75,13 +98,19 @@ func (c *reporter) PostCall(err error, duration time.Duration) {
 
 // PostMsgSend implement
 func (c *reporter) PostMsgSend(resp interface{}, err error, duration time.Duration) {
+	payloadDecision := shouldLog(c.opts.shouldLog, FullMethod(c.service, c.method), err)
+	if c.stream != nil && payloadDecision.Stream {
+		if err == nil {
+			c.streamMessage(resp, true, payloadDecision)
+		}
+		return
+	}
 	if c.startCallLogged {
 		return
 	}
-	payloadDecision := shouldLog(c.opts.shouldLog, FullMethod(c.service, c.method), err)
 	if err == nil {
 		if payloadDecision.Response {
-			c.ctx = logging.InjectLogField(c.ctx, "response_"+keyContent, bodyString(resp, payloadDecision.ClearBytes))
+			c.ctx = logging.InjectLogField(c.ctx, "response_"+keyContent, c.opts.redactor.Payload(resp, payloadDecision.ClearBytes))
 		}
 		if c.opts.responseFieldExtractorFunc != nil {
 			data := c.opts.responseFieldExtractorFunc(FullMethod(c.service, c.method), resp)
@@ -94,24 +123,6 @@ func (c *reporter) PostMsgSend(resp interface{}, err error, duration time.Durati
 	}
 }
 
-func bodyString(val interface{}, clearBytes bool) (logStr string) {
-	if bodyProto, ok := val.(proto.Message); ok {
-		if clearBytes {
-			clearMessageBytes(proto.Clone(bodyProto).ProtoReflect())
-		}
-		b, err := protojson.Marshal(bodyProto)
-		if err != nil {
-			logStr = "error:" + err.Error()
-		} else {
-			if len(b) > 2048 {
-				b = b[:2048]
-			}
-			logStr = string(b)
-		}
-	}
-	return
-}
-
 func shouldLog(decider Decider, fullMethod string, err error) PayloadDecision {
 	if strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") {
 		return PayloadDecision{}
@@ -125,6 +136,14 @@ func FullMethod(service, method string) string {
 
 // PostMsgReceive implement
 func (c *reporter) PostMsgReceive(req interface{}, err error, duration time.Duration) {
+	if c.stream != nil {
+		if payloadDecision := shouldLog(c.opts.shouldLog, FullMethod(c.service, c.method), err); payloadDecision.Stream {
+			if err == nil {
+				c.streamMessage(req, false, payloadDecision)
+			}
+			return
+		}
+	}
 	if c.startCallLogged {
 		return
 	}
@@ -138,7 +157,7 @@ func (c *reporter) PostMsgReceive(req interface{}, err error, duration time.Dura
 	}
 	payloadDecision := shouldLog(c.opts.shouldLog, FullMethod(c.service, c.method), err)
 	if payloadDecision.Request && err == nil {
-		c.ctx = logging.InjectLogField(c.ctx, keyRequestContent, bodyString(req, payloadDecision.ClearBytes))
+		c.ctx = logging.InjectLogField(c.ctx, keyRequestContent, c.opts.redactor.Payload(req, payloadDecision.ClearBytes))
 	}
 	if payloadDecision.Events == logging.StartCall {
 		c.startCallLogged = true
@@ -159,6 +178,61 @@ func (c *reporter) logMessage(logger logging.Logger, err error, msg string, dura
 	logger.Log(c.ctx, logLevel, msg, fields...)
 }
 
+// streamMessage count the message of stream, and log the payload if sampled.
+func (c *reporter) streamMessage(m interface{}, send bool, d PayloadDecision) {
+	size := 0
+	if pm, ok := m.(proto.Message); ok {
+		size = proto.Size(pm)
+	}
+	count, bytes, first, direction := &c.stream.recvCount, &c.stream.recvBytes, &c.stream.firstRecv, "recv"
+	if send {
+		count, bytes, first, direction = &c.stream.sendCount, &c.stream.sendBytes, &c.stream.firstSend, "send"
+	}
+	seq := count.Add(1)
+	bytes.Add(int64(size))
+	if seq == 1 {
+		first.Store(int64(time.Since(c.start)))
+	}
+
+	// the request is sent by client and received by server
+	isRequest := send == (c.kind == logging.KindClientFieldValue)
+	if (isRequest && !d.Request) || (!isRequest && !d.Response) {
+		return
+	}
+	if n := int64(d.StreamSample); n > 1 && seq != 1 && seq%n != 0 {
+		return
+	}
+	fields := logging.ExtractFields(c.ctx)
+	fields = append(fields,
+		"stream.direction", direction,
+		"stream.seq", seq,
+		"stream."+keyContent, c.opts.redactor.Payload(m, d.ClearBytes),
+	)
+	c.logger.Log(c.ctx, logging.LevelDebug, "stream message", fields...)
+}
+
+// fields the stats fields of the finish line
+func (s *streamStats) fields() logging.Fields {
+	fields := logging.Fields{
+		"stream.recv_count", s.recvCount.Load(),
+		"stream.send_count", s.sendCount.Load(),
+		"stream.recv_bytes", s.recvBytes.Load(),
+		"stream.send_bytes", s.sendBytes.Load(),
+	}
+	if d := s.firstRecv.Load(); d > 0 {
+		fields = append(fields, "stream.first_recv_ms", msString(time.Duration(d)))
+	}
+	if d := s.firstSend.Load(); d > 0 {
+		fields = append(fields, "stream.first_send_ms", msString(time.Duration(d)))
+	}
+	return fields
+}
+
+// msString format the duration as milliseconds like time_ms
+func msString(d time.Duration) string {
+	return fmt.Sprintf("%v", float32(d.Nanoseconds()/1000)/1000)
+}
+
 const keyContent = "content"
 
 var keyRequestContent = "request." + keyContent
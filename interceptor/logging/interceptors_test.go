@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type logLine struct {
+	msg    string
+	fields map[string]any
+}
+
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []logLine
+}
+
+func (l *captureLogger) Log(_ context.Context, _ logging.Level, msg string, fields ...any) {
+	m := map[string]any{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		m[fields[i].(string)] = fields[i+1]
+	}
+	l.mu.Lock()
+	l.lines = append(l.lines, logLine{msg: msg, fields: m})
+	l.mu.Unlock()
+}
+
+func TestStreamLogging(t *testing.T) {
+	capture := &captureLogger{}
+	r := &reportable{logger: capture, opts: evaluateOpt([]Option{WithDecider(func(string, error) PayloadDecision {
+		return PayloadDecision{Events: logging.FinishCall, Request: true, Stream: true, StreamSample: 2}
+	})})}
+	rep, _ := r.ServerReporter(context.Background(), interceptors.CallMeta{
+		Typ: interceptors.BidiStream, Service: "pkg.Chat", Method: "Talk",
+	})
+
+	for _, s := range []string{"a", "bb", "ccc"} {
+		rep.PostMsgReceive(wrapperspb.String(s), nil, 0)
+	}
+	rep.PostMsgSend(wrapperspb.String("ok"), nil, 0)
+	rep.PostMsgReceive(&wrapperspb.StringValue{}, io.EOF, 0)
+	rep.PostCall(nil, 0)
+
+	var payloads []any
+	var finish *logLine
+	for i, line := range capture.lines {
+		switch line.msg {
+		case "stream message":
+			if line.fields["stream.direction"] != "recv" {
+				t.Errorf("the response payload should not be logged: %v", line.fields)
+			}
+			payloads = append(payloads, line.fields["stream."+keyContent])
+		case "finished call":
+			finish = &capture.lines[i]
+		}
+	}
+	// the first and every 2nd message
+	if len(payloads) != 2 || payloads[0] != `"a"` || payloads[1] != `"bb"` {
+		t.Errorf("unexpected sampled payloads %v", payloads)
+	}
+	if finish == nil {
+		t.Fatal("expected the finish line")
+	}
+	if finish.fields["stream.recv_count"] != int64(3) || finish.fields["stream.send_count"] != int64(1) ||
+		finish.fields["stream.recv_bytes"] != int64(3+4+5) || finish.fields["stream.first_recv_ms"] == nil {
+		t.Errorf("unexpected stream stats %v", finish.fields)
+	}
+}
+
+func TestUnaryLoggingWithStreamDecision(t *testing.T) {
+	capture := &captureLogger{}
+	r := &reportable{logger: capture, opts: evaluateOpt([]Option{WithDecider(func(string, error) PayloadDecision {
+		return PayloadDecision{Events: logging.FinishCall, Request: true, Stream: true}
+	})})}
+	rep, _ := r.ServerReporter(context.Background(), interceptors.CallMeta{
+		Typ: interceptors.Unary, Service: "pkg.Chat", Method: "Get",
+	})
+	rep.PostMsgReceive(wrapperspb.String("a"), nil, 0)
+	rep.PostMsgSend(wrapperspb.String("b"), nil, 0)
+	rep.PostCall(nil, 0)
+
+	if len(capture.lines) != 1 || capture.lines[0].fields[keyRequestContent] != `"a"` ||
+		capture.lines[0].fields["stream.recv_count"] != nil {
+		t.Errorf("unexpected unary log %v", capture.lines)
+	}
+}
@@ -9,6 +9,7 @@ var (
 	defaultOptions = &options{
 		shouldLog: DefaultLoggingDeciderMethod,
 		levelFunc: DefaultCodeToLevel,
+		redactor:  defaultRedactor,
 	}
 )
 
@@ -18,6 +19,11 @@ type PayloadDecision struct {
 	Request    bool
 	Response   bool
 	ClearBytes bool
+	// Stream log the streaming RPCs per message, the message counts, bytes and time to first message
+	// are added to the finish line, and the payloads are logged if Request or Response.
+	Stream bool
+	// StreamSample log the payloads of the first and every n-th message of streams, 1 by default.
+	StreamSample int
 }
 
 type options struct {
@@ -25,6 +31,7 @@ type options struct {
 	shouldLog                  Decider
 	requestFieldExtractorFunc  RequestFieldExtractorFunc
 	responseFieldExtractorFunc ResponseFieldExtractorFunc
+	redactor                   *Redactor
 }
 
 // Option the Options for this module
@@ -44,6 +51,16 @@ func WithLevels(f logging.CodeToLevel) Option {
 	}
 }
 
+// WithRedactor customizes the redactor of the request and response payloads,
+// by default the fields marked by `(ginny.sensitive) = true` are masked and the payloads are truncated at 2048 bytes.
+func WithRedactor(r *Redactor) Option {
+	return func(o *options) {
+		if r != nil {
+			o.redactor = r
+		}
+	}
+}
+
 // WithResponseFieldExtractorFunc customizes the function for extracting log fields from protobuf messages, for
 // unary and server-streamed methods only.
 func WithResponseFieldExtractorFunc(f ResponseFieldExtractorFunc) Option {
@@ -0,0 +1,290 @@
+// Package logging implements grpc logging middleware.
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goriller/ginny/proto/ginny"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DefaultMaxPayloadSize the default max size of the logged payload
+const DefaultMaxPayloadSize = 2048
+
+const maskedValue = "******"
+
+// Strategy the strategy to mask the sensitive value
+type Strategy int
+
+const (
+	// StrategyMask replace the value by ******, the non string values are dropped.
+	StrategyMask Strategy = iota
+	// StrategyDrop drop the field.
+	StrategyDrop
+	// StrategyHash replace the value by the short sha256 hash, so the equal values can be correlated.
+	StrategyHash
+	// StrategyLast4 keep the last 4 characters only, such as ****1234.
+	StrategyLast4
+)
+
+// ParseStrategy parse the strategy name: mask, drop, hash or last4.
+func ParseStrategy(s string) (Strategy, bool) {
+	switch strings.ToLower(s) {
+	case "", "mask":
+		return StrategyMask, true
+	case "drop":
+		return StrategyDrop, true
+	case "hash":
+		return StrategyHash, true
+	case "last4":
+		return StrategyLast4, true
+	}
+	return StrategyMask, false
+}
+
+// Redactor mask the sensitive fields of the payloads in logs, the fields are marked by the
+// `(ginny.sensitive) = true` field option or configured by paths.
+type Redactor struct {
+	strategy Strategy
+	paths    map[string]Strategy
+	maxSize  int
+}
+
+// RedactOption the option of redactor
+type RedactOption func(*Redactor)
+
+// WithStrategy set the strategy of the fields marked by `(ginny.sensitive)`, default StrategyMask.
+func WithStrategy(s Strategy) RedactOption {
+	return func(r *Redactor) {
+		r.strategy = s
+	}
+}
+
+// WithPaths mask the fields by the proto names, such as `user.password` from the root message,
+// or the field at any depth if there is no dot, such as `token`. For the access logs, the paths
+// are matched with the tag keys and query parameters.
+func WithPaths(s Strategy, paths ...string) RedactOption {
+	return func(r *Redactor) {
+		for _, p := range paths {
+			if p != "" {
+				r.paths[p] = s
+			}
+		}
+	}
+}
+
+// WithMaxSize set the max size of the logged payload, default DefaultMaxPayloadSize, no limit if < 0.
+func WithMaxSize(n int) RedactOption {
+	return func(r *Redactor) {
+		r.maxSize = n
+	}
+}
+
+// NewRedactor new redactor.
+func NewRedactor(opts ...RedactOption) *Redactor {
+	r := &Redactor{paths: map[string]Strategy{}, maxSize: DefaultMaxPayloadSize}
+	for _, fn := range opts {
+		fn(r)
+	}
+	return r
+}
+
+// defaultRedactor mask the `(ginny.sensitive)` fields only
+var defaultRedactor = NewRedactor()
+
+// Payload marshal the message to json with the sensitive fields masked, and truncate it to the max size.
+func (r *Redactor) Payload(val interface{}, clearBytes bool) string {
+	m, ok := val.(proto.Message)
+	if !ok {
+		return ""
+	}
+	m = proto.Clone(m)
+	r.redactMessage(m.ProtoReflect(), "", clearBytes)
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return "error:" + err.Error()
+	}
+	return r.truncate(b)
+}
+
+// Value mask the value by the key, for the access logs.
+func (r *Redactor) Value(key, value string) (string, bool) {
+	s, ok := r.paths[key]
+	if !ok {
+		if i := strings.LastIndexByte(key, '.'); i >= 0 {
+			s, ok = r.paths[key[i+1:]]
+		}
+	}
+	if !ok {
+		return value, false
+	}
+	if s == StrategyDrop {
+		return "", true
+	}
+	return maskString(s, value), true
+}
+
+// strategyOf the strategy of the field, false if it is not sensitive
+func (r *Redactor) strategyOf(fd pref.FieldDescriptor, path string) (Strategy, bool) {
+	if len(r.paths) > 0 {
+		if s, ok := r.paths[path]; ok {
+			return s, true
+		}
+		if s, ok := r.paths[string(fd.Name())]; ok {
+			return s, true
+		}
+	}
+	if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts != nil &&
+		proto.GetExtension(opts, ginny.E_Sensitive).(bool) {
+		return r.strategy, true
+	}
+	return 0, false
+}
+
+// redactMessage
+func (r *Redactor) redactMessage(m pref.Message, prefix string, clearBytes bool) {
+	m.Range(func(fd pref.FieldDescriptor, val pref.Value) bool {
+		path := string(fd.Name())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		if s, ok := r.strategyOf(fd, path); ok {
+			r.redactField(m, fd, val, s)
+			return true
+		}
+		switch {
+		case fd.IsList():
+			if isMessage(fd) {
+				list := val.List()
+				for i := 0; i < list.Len(); i++ {
+					r.redactMessage(list.Get(i).Message(), path, clearBytes)
+				}
+			} else if clearBytes && fd.Kind() == pref.BytesKind {
+				m.Clear(fd)
+			}
+		case fd.IsMap():
+			if isMessage(fd.MapValue()) {
+				val.Map().Range(func(_ pref.MapKey, v pref.Value) bool {
+					r.redactMessage(v.Message(), path, clearBytes)
+					return true
+				})
+			} else if clearBytes && fd.MapValue().Kind() == pref.BytesKind {
+				m.Clear(fd)
+			}
+		case isMessage(fd):
+			r.redactMessage(val.Message(), path, clearBytes)
+		case clearBytes && fd.Kind() == pref.BytesKind:
+			m.Clear(fd)
+		}
+		return true
+	})
+}
+
+// redactField mask the string values, the others are dropped.
+func (r *Redactor) redactField(m pref.Message, fd pref.FieldDescriptor, val pref.Value, s Strategy) {
+	if s == StrategyDrop {
+		m.Clear(fd)
+		return
+	}
+	switch {
+	case fd.IsList():
+		if !isMaskable(fd) {
+			m.Clear(fd)
+			return
+		}
+		list := val.List()
+		for i := 0; i < list.Len(); i++ {
+			list.Set(i, maskValue(fd, s, list.Get(i)))
+		}
+	case fd.IsMap():
+		if !isMaskable(fd.MapValue()) {
+			m.Clear(fd)
+			return
+		}
+		mm := val.Map()
+		mm.Range(func(k pref.MapKey, v pref.Value) bool {
+			mm.Set(k, maskValue(fd.MapValue(), s, v))
+			return true
+		})
+	case isMaskable(fd):
+		m.Set(fd, maskValue(fd, s, val))
+	default:
+		m.Clear(fd)
+	}
+}
+
+// isMessage
+func isMessage(fd pref.FieldDescriptor) bool {
+	return fd.Kind() == pref.MessageKind || fd.Kind() == pref.GroupKind
+}
+
+// isMaskable
+func isMaskable(fd pref.FieldDescriptor) bool {
+	return fd.Kind() == pref.StringKind || fd.Kind() == pref.BytesKind
+}
+
+// maskValue
+func maskValue(fd pref.FieldDescriptor, s Strategy, v pref.Value) pref.Value {
+	if fd.Kind() == pref.BytesKind {
+		return pref.ValueOfBytes([]byte(maskString(s, string(v.Bytes()))))
+	}
+	return pref.ValueOfString(maskString(s, v.String()))
+}
+
+// maskString
+func maskString(s Strategy, v string) string {
+	switch s {
+	case StrategyHash:
+		sum := sha256.Sum256([]byte(v))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	case StrategyLast4:
+		if utf8.RuneCountInString(v) <= 4 {
+			return "****"
+		}
+		runes := []rune(v)
+		return "****" + string(runes[len(runes)-4:])
+	default:
+		return maskedValue
+	}
+}
+
+// truncate cut the json at the rune boundary and outside of the escape sequence,
+// and mark it with the original size.
+func (r *Redactor) truncate(b []byte) string {
+	if r.maxSize < 0 || len(b) <= r.maxSize {
+		return string(b)
+	}
+	n := r.maxSize
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	// do not split the escape sequence like \" or \u00e9
+	for i := n - 1; i >= 0 && i >= n-6; i-- {
+		if b[i] != '\\' {
+			continue
+		}
+		// count the preceding backslashes, an odd count means b[i] is escaped itself
+		j := i
+		for j > 0 && b[j-1] == '\\' {
+			j--
+		}
+		if (i-j)%2 == 0 {
+			escLen := 2
+			if i+1 < len(b) && b[i+1] == 'u' {
+				escLen = 6
+			}
+			if i+escLen > n {
+				n = i
+			}
+			break
+		}
+	}
+	return string(b[:n]) + "...(truncated " + strconv.Itoa(len(b)) + " bytes)"
+}
@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/goriller/ginny/proto/ginny"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	pref "google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newUser build the dynamic message:
+//
+//	message User {
+//	  string name = 1;
+//	  string password = 2 [(ginny.sensitive) = true];
+//	  string phone = 3;
+//	  bytes avatar = 4;
+//	  repeated string tokens = 5 [(ginny.sensitive) = true];
+//	  Address address = 6;
+//	}
+//	message Address { string street = 1; int64 code = 2; }
+func newUser(t *testing.T) pref.Message {
+	sensitive := &descriptorpb.FieldOptions{}
+	proto.SetExtension(sensitive, ginny.E_Sensitive, true)
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+			Options:  opts,
+		}
+	}
+	tokens := field("tokens", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, sensitive)
+	tokens.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	address := field("address", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, nil)
+	address.TypeName = proto.String(".test.Address")
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/user.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"ginny/options.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+				field("password", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, sensitive),
+				field("phone", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+				field("avatar", 4, descriptorpb.FieldDescriptorProto_TYPE_BYTES, nil),
+				tokens,
+				address,
+			},
+		}, {
+			Name: proto.String("Address"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("street", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+				field("code", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := fd.Messages().ByName("User")
+	m := dynamicpb.NewMessage(md)
+	m.Set(md.Fields().ByName("name"), pref.ValueOfString("gopher"))
+	m.Set(md.Fields().ByName("password"), pref.ValueOfString("secret"))
+	m.Set(md.Fields().ByName("phone"), pref.ValueOfString("13800001234"))
+	m.Set(md.Fields().ByName("avatar"), pref.ValueOfBytes([]byte("png")))
+	list := m.Mutable(md.Fields().ByName("tokens")).List()
+	list.Append(pref.ValueOfString("t1"))
+	list.Append(pref.ValueOfString("t2"))
+	addr := m.Mutable(md.Fields().ByName("address")).Message()
+	addr.Set(addr.Descriptor().Fields().ByName("street"), pref.ValueOfString("main st"))
+	addr.Set(addr.Descriptor().Fields().ByName("code"), pref.ValueOfInt64(100))
+	return m
+}
+
+func TestRedactorPayload(t *testing.T) {
+	m := newUser(t)
+	r := NewRedactor(
+		WithStrategy(StrategyHash),
+		WithPaths(StrategyLast4, "phone"),
+		WithPaths(StrategyDrop, "address.code"),
+		WithPaths(StrategyMask, "street"),
+	)
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(r.Payload(m.Interface(), true)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["name"] != "gopher" || got["phone"] != "****1234" || got["avatar"] != nil {
+		t.Errorf("unexpected payload %v", got)
+	}
+	if p, _ := got["password"].(string); !strings.HasPrefix(p, "sha256:") || strings.Contains(p, "secret") {
+		t.Errorf("expected the hashed password, got %v", got["password"])
+	}
+	if tokens, _ := got["tokens"].([]interface{}); len(tokens) != 2 || tokens[0] == "t1" {
+		t.Errorf("expected the hashed tokens, got %v", got["tokens"])
+	}
+	if addr, _ := got["address"].(map[string]interface{}); addr["street"] != maskedValue || addr["code"] != nil {
+		t.Errorf("unexpected address %v", got["address"])
+	}
+
+	// the original message is untouched
+	if v := m.Get(m.Descriptor().Fields().ByName("password")).String(); v != "secret" {
+		t.Errorf("the original message is modified: %s", v)
+	}
+	// default redactor mask the sensitive fields only
+	if p := defaultRedactor.Payload(m.Interface(), false); !strings.Contains(p, `"password":"******"`) || !strings.Contains(p, "13800001234") {
+		t.Errorf("unexpected default payload %s", p)
+	}
+}
+
+func TestRedactorTruncate(t *testing.T) {
+	r := NewRedactor(WithMaxSize(12))
+	tests := map[string]string{
+		`{"a":"short"}`:          `{"a":"short"...(truncated 13 bytes)`,
+		`{"a":"abcdef"}`:         `{"a":"abcdef...(truncated 14 bytes)`,
+		`{"a":"abcde\"fgh"}`:     `{"a":"abcde...(truncated 18 bytes)`,
+		`{"a":"ab\u00e9cdefgh"}`: `{"a":"ab...(truncated 22 bytes)`,
+		`{"a":"你好世界"}`:           `{"a":"你好...(truncated 20 bytes)`,
+		`{"a":"ok"}`:             `{"a":"ok"}`,
+	}
+	for in, want := range tests {
+		if got := r.truncate([]byte(in)); got != want {
+			t.Errorf("truncate %s: expected %s, got %s", in, want, got)
+		}
+	}
+}
+
+func TestRedactorValue(t *testing.T) {
+	r := NewRedactor(WithPaths(StrategyLast4, "card"), WithPaths(StrategyDrop, "request.token"))
+	if v, ok := r.Value("request.card", "4111111111111111"); !ok || v != "****1111" {
+		t.Errorf("unexpected card %s", v)
+	}
+	if v, ok := r.Value("request.token", "abc"); !ok || v != "" {
+		t.Errorf("unexpected token %s", v)
+	}
+	if v, ok := r.Value("name", "gopher"); ok || v != "gopher" {
+		t.Errorf("unexpected name %s", v)
+	}
+}
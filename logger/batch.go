@@ -2,52 +2,63 @@ package logger
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-// BatchLogger 批处理日志器
-type BatchLogger struct {
-	logger    *zap.Logger
-	buffer    []logEntry
-	mu        sync.Mutex
-	flushSize int           // 批量大小
-	flushTime time.Duration // 刷新间隔
-	stopCh    chan struct{}
-	wg        sync.WaitGroup
-
-	// 性能统计
-	stats BatchStats
-}
+// OverflowPolicy 缓冲区满时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest 丢弃新的日志（默认）
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest 丢弃缓冲区中最旧的日志
+	OverflowDropOldest
+	// OverflowBlock 阻塞等待空间，超过BlockTimeout后丢弃
+	OverflowBlock
+	// OverflowSpill 直接同步写入
+	OverflowSpill
+)
 
-// logEntry 日志条目
-type logEntry struct {
-	level   zapcore.Level
-	message string
-	fields  []zap.Field
-	time    time.Time
+// BatchLogger 批处理日志器，日志写入无锁环形缓冲区，由后台协程批量写出。
+// Error及以上级别的日志会先刷新缓冲区再同步写入，保证不丢失且顺序正确。
+type BatchLogger struct {
+	logger *zap.Logger
+	// skipped 跳过Debug等方法自身的调用栈
+	skipped *zap.Logger
+	b       *batcher
 }
 
 // BatchStats 批处理统计
 type BatchStats struct {
-	TotalLogs    int64   `json:"total_logs"`
-	BatchedLogs  int64   `json:"batched_logs"`
-	FlushCount   int64   `json:"flush_count"`
-	DroppedLogs  int64   `json:"dropped_logs"`
+	TotalLogs    int64   `json:"total_logs"`   // 写入的日志数，不含丢弃的
+	BatchedLogs  int64   `json:"batched_logs"` // 批量写出的日志数
+	FlushCount   int64   `json:"flush_count"`  // 刷新次数
+	DroppedLogs  int64   `json:"dropped_logs"` // 丢弃的日志数
+	SpilledLogs  int64   `json:"spilled_logs"` // 缓冲区满时同步写入的日志数
+	BlockedLogs  int64   `json:"blocked_logs"` // 等待过空间的日志数
+	Pending      int64   `json:"pending"`      // 缓冲区中的日志数
 	AvgBatchSize float64 `json:"avg_batch_size"`
 }
 
 // BatchConfig 批处理配置
 type BatchConfig struct {
-	FlushSize     int           // 批量大小，默认100
-	FlushInterval time.Duration // 刷新间隔，默认1秒
-	BufferSize    int           // 缓冲区大小，默认1000
+	Name          string         // 指标的name标签，默认default
+	FlushSize     int            // 批量大小，默认100
+	FlushInterval time.Duration  // 刷新间隔，默认1秒
+	BufferSize    int            // 缓冲区大小，默认1000，向上取整为2的幂
+	Overflow      OverflowPolicy // 缓冲区满时的策略，默认OverflowDropNewest
+	BlockTimeout  time.Duration  // OverflowBlock的最长等待时间，默认100毫秒
 }
 
 // NewBatchLogger 创建批处理日志器
 func NewBatchLogger(baseLogger *zap.Logger, config BatchConfig) *BatchLogger {
+	if config.Name == "" {
+		config.Name = "default"
+	}
 	if config.FlushSize <= 0 {
 		config.FlushSize = 100
 	}
@@ -57,176 +68,391 @@ func NewBatchLogger(baseLogger *zap.Logger, config BatchConfig) *BatchLogger {
 	if config.BufferSize <= 0 {
 		config.BufferSize = 1000
 	}
+	if config.BlockTimeout <= 0 {
+		config.BlockTimeout = 100 * time.Millisecond
+	}
 
+	b := &batcher{
+		config: config,
+		ring:   newRing(config.BufferSize),
+		kickCh: make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+	}
+	space := make(chan struct{})
+	b.spaceCh.Store(&space)
 	bl := &BatchLogger{
-		logger:    baseLogger,
-		buffer:    make([]logEntry, 0, config.BufferSize),
-		flushSize: config.FlushSize,
-		flushTime: config.FlushInterval,
-		stopCh:    make(chan struct{}),
+		logger: baseLogger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return &batchCore{Core: c, b: b}
+		})),
+		b: b,
 	}
+	bl.skipped = bl.logger.WithOptions(zap.AddCallerSkip(1))
 
 	// 启动批处理协程
-	bl.wg.Add(1)
-	go bl.batchProcessor()
+	b.wg.Add(1)
+	go b.batchProcessor()
+	batchers.Store(b, struct{}{})
 
 	return bl
 }
 
+// Logger 返回批处理的zap logger，With的字段同样批量写出
+func (bl *BatchLogger) Logger() *zap.Logger {
+	return bl.logger
+}
+
+// With 返回带字段的批处理日志器，共享缓冲区
+func (bl *BatchLogger) With(fields ...zap.Field) *zap.Logger {
+	return bl.logger.With(fields...)
+}
+
 // Debug 批处理Debug日志
 func (bl *BatchLogger) Debug(msg string, fields ...zap.Field) {
-	bl.addEntry(zapcore.DebugLevel, msg, fields)
+	bl.skipped.Debug(msg, fields...)
 }
 
 // Info 批处理Info日志
 func (bl *BatchLogger) Info(msg string, fields ...zap.Field) {
-	bl.addEntry(zapcore.InfoLevel, msg, fields)
+	bl.skipped.Info(msg, fields...)
 }
 
 // Warn 批处理Warn日志
 func (bl *BatchLogger) Warn(msg string, fields ...zap.Field) {
-	bl.addEntry(zapcore.WarnLevel, msg, fields)
+	bl.skipped.Warn(msg, fields...)
 }
 
-// Error 批处理Error日志
+// Error Error日志（刷新缓冲区后同步写入）
 func (bl *BatchLogger) Error(msg string, fields ...zap.Field) {
-	bl.addEntry(zapcore.ErrorLevel, msg, fields)
+	bl.skipped.Error(msg, fields...)
 }
 
-// Fatal Fatal日志（立即刷新）
+// Fatal Fatal日志（刷新缓冲区后同步写入并退出）
 func (bl *BatchLogger) Fatal(msg string, fields ...zap.Field) {
-	// Fatal级别立即写入，不进行批处理
-	bl.logger.Fatal(msg, fields...)
+	bl.skipped.Fatal(msg, fields...)
 }
 
-// addEntry 添加日志条目到缓冲区
-func (bl *BatchLogger) addEntry(level zapcore.Level, msg string, fields []zap.Field) {
-	entry := logEntry{
-		level:   level,
-		message: msg,
-		fields:  fields,
-		time:    time.Now(),
-	}
+// Flush 手动刷新缓冲区
+func (bl *BatchLogger) Flush() {
+	bl.b.flush()
+}
 
-	bl.mu.Lock()
-	defer bl.mu.Unlock()
+// Stats 获取批处理统计
+func (bl *BatchLogger) Stats() BatchStats {
+	return bl.b.stats()
+}
 
-	// 检查缓冲区是否已满
-	if len(bl.buffer) >= cap(bl.buffer) {
-		bl.stats.DroppedLogs++
-		return
+// Close 关闭批处理日志器，之后的日志同步写入
+func (bl *BatchLogger) Close() error {
+	b := bl.b
+	if !b.closed.CompareAndSwap(false, true) {
+		return nil
 	}
+	close(b.stopCh)
+	b.wg.Wait()
+	batchers.Delete(b)
+	return bl.logger.Sync()
+}
 
-	bl.buffer = append(bl.buffer, entry)
-	bl.stats.TotalLogs++
+// batchCore 将日志写入缓冲区的core
+type batchCore struct {
+	zapcore.Core
+	b *batcher
+}
+
+// With 实现zapcore.Core
+func (c *batchCore) With(fields []zapcore.Field) zapcore.Core {
+	return &batchCore{Core: c.Core.With(fields), b: c.b}
+}
+
+// Check 实现zapcore.Core
+func (c *batchCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
 
-	// 检查是否需要立即刷新
-	if len(bl.buffer) >= bl.flushSize {
-		bl.flushBuffer()
+// Write 实现zapcore.Core，采样等由下游core在写入时决定
+func (c *batchCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	ce := c.Core.Check(e, nil)
+	if ce == nil {
+		return nil
 	}
+	c.b.add(ce, fields)
+	return nil
+}
+
+// Sync 实现zapcore.Core，先刷新缓冲区
+func (c *batchCore) Sync() error {
+	c.b.flush()
+	return c.Core.Sync()
+}
+
+// batchItem 缓冲区中的日志
+type batchItem struct {
+	ce     *zapcore.CheckedEntry
+	fields []zapcore.Field
 }
 
-// flushBuffer 刷新缓冲区（需要持有锁）
-func (bl *BatchLogger) flushBuffer() {
-	if len(bl.buffer) == 0 {
+// batcher 缓冲区及批量写出，由BatchLogger及其With共享
+type batcher struct {
+	config BatchConfig
+	ring   *ring
+	kickCh chan struct{}
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+	closed atomic.Bool
+	// spaceCh 每次刷新后关闭并替换，唤醒等待空间的写入
+	spaceCh atomic.Pointer[chan struct{}]
+
+	flushMu sync.Mutex
+	batch   []batchItem
+
+	total   atomic.Int64
+	batched atomic.Int64
+	flushes atomic.Int64
+	dropped atomic.Int64
+	spilled atomic.Int64
+	blocked atomic.Int64
+}
+
+// add 添加日志到缓冲区，缓冲区满时按策略处理
+func (b *batcher) add(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	if ce.Level >= zapcore.ErrorLevel || b.closed.Load() {
+		// 保证Error及以上级别不丢失且在之前的日志之后写入
+		b.flush()
+		b.total.Add(1)
+		ce.Write(fields...)
+		return
+	}
+
+	item := batchItem{ce: ce, fields: fields}
+	if b.ring.push(item) {
+		b.total.Add(1)
+		if b.ring.len() >= b.config.FlushSize {
+			b.kick()
+		}
 		return
 	}
 
-	// 复制缓冲区以避免长时间持锁
-	batch := make([]logEntry, len(bl.buffer))
-	copy(batch, bl.buffer)
-	bl.buffer = bl.buffer[:0] // 重置缓冲区
+	switch b.config.Overflow {
+	case OverflowDropOldest:
+		for !b.ring.push(item) {
+			if _, ok := b.ring.pop(); ok {
+				b.dropped.Add(1)
+				b.total.Add(-1)
+			}
+		}
+		b.total.Add(1)
+		b.kick()
+	case OverflowBlock:
+		b.blocked.Add(1)
+		if b.wait(item) {
+			b.total.Add(1)
+		} else {
+			b.dropped.Add(1)
+		}
+	case OverflowSpill:
+		b.spilled.Add(1)
+		b.total.Add(1)
+		ce.Write(fields...)
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// wait 等待刷新腾出空间，超时返回false
+func (b *batcher) wait(item batchItem) bool {
+	timer := time.NewTimer(b.config.BlockTimeout)
+	defer timer.Stop()
+	for {
+		space := *b.spaceCh.Load()
+		if b.ring.push(item) {
+			return true
+		}
+		b.kick()
+		select {
+		case <-space:
+		case <-timer.C:
+			return false
+		case <-b.stopCh:
+			// 已关闭，同步写入
+			item.ce.Write(item.fields...)
+			return true
+		}
+	}
+}
 
-	bl.stats.FlushCount++
-	bl.stats.BatchedLogs += int64(len(batch))
-	if bl.stats.FlushCount > 0 {
-		bl.stats.AvgBatchSize = float64(bl.stats.BatchedLogs) / float64(bl.stats.FlushCount)
+// kick 通知后台协程刷新
+func (b *batcher) kick() {
+	select {
+	case b.kickCh <- struct{}{}:
+	default:
 	}
+}
+
+// flush 写出缓冲区中的日志，复用批量slice
+func (b *batcher) flush() {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
 
-	// 释放锁后写入日志
-	bl.mu.Unlock()
-	bl.writeBatch(batch)
-	bl.mu.Lock()
-}
-
-// writeBatch 写入批量日志
-func (bl *BatchLogger) writeBatch(batch []logEntry) {
-	for _, entry := range batch {
-		switch entry.level {
-		case zapcore.DebugLevel:
-			bl.logger.Debug(entry.message, entry.fields...)
-		case zapcore.InfoLevel:
-			bl.logger.Info(entry.message, entry.fields...)
-		case zapcore.WarnLevel:
-			bl.logger.Warn(entry.message, entry.fields...)
-		case zapcore.ErrorLevel:
-			bl.logger.Error(entry.message, entry.fields...)
+	for n := b.ring.cap(); n > 0; n-- {
+		item, ok := b.ring.pop()
+		if !ok {
+			break
 		}
+		b.batch = append(b.batch, item)
+	}
+	if len(b.batch) == 0 {
+		return
 	}
+	// 唤醒等待空间的写入
+	space := make(chan struct{})
+	close(*b.spaceCh.Swap(&space))
+
+	for i := range b.batch {
+		b.batch[i].ce.Write(b.batch[i].fields...)
+		b.batch[i] = batchItem{}
+	}
+	b.batched.Add(int64(len(b.batch)))
+	b.flushes.Add(1)
+	b.batch = b.batch[:0]
 }
 
 // batchProcessor 批处理协程
-func (bl *BatchLogger) batchProcessor() {
-	defer bl.wg.Done()
+func (b *batcher) batchProcessor() {
+	defer b.wg.Done()
 
-	ticker := time.NewTicker(bl.flushTime)
+	ticker := time.NewTicker(b.config.FlushInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			bl.mu.Lock()
-			bl.flushBuffer()
-			bl.mu.Unlock()
-		case <-bl.stopCh:
+			b.flush()
+		case <-b.kickCh:
+			b.flush()
+		case <-b.stopCh:
 			// 最后一次刷新
-			bl.mu.Lock()
-			bl.flushBuffer()
-			bl.mu.Unlock()
+			b.flush()
 			return
 		}
 	}
 }
 
-// Flush 手动刷新缓冲区
-func (bl *BatchLogger) Flush() {
-	bl.mu.Lock()
-	bl.flushBuffer()
-	bl.mu.Unlock()
+// stats
+func (b *batcher) stats() BatchStats {
+	s := BatchStats{
+		TotalLogs:   b.total.Load(),
+		BatchedLogs: b.batched.Load(),
+		FlushCount:  b.flushes.Load(),
+		DroppedLogs: b.dropped.Load(),
+		SpilledLogs: b.spilled.Load(),
+		BlockedLogs: b.blocked.Load(),
+		Pending:     int64(b.ring.len()),
+	}
+	if s.FlushCount > 0 {
+		s.AvgBatchSize = float64(s.BatchedLogs) / float64(s.FlushCount)
+	}
+	return s
 }
 
-// Stats 获取批处理统计
-func (bl *BatchLogger) Stats() BatchStats {
-	bl.mu.Lock()
-	defer bl.mu.Unlock()
-	return bl.stats
+// ring 有界无锁多生产者多消费者环形队列
+type ring struct {
+	mask  uint64
+	slots []ringSlot
+	_     [56]byte
+	head  atomic.Uint64
+	_     [56]byte
+	tail  atomic.Uint64
 }
 
-// Close 关闭批处理日志器
-func (bl *BatchLogger) Close() error {
-	close(bl.stopCh)
-	bl.wg.Wait()
-	return nil
+// ringSlot seq标记槽位可写（等于tail）或可读（等于head+1）
+type ringSlot struct {
+	seq  atomic.Uint64
+	item batchItem
+}
+
+// newRing 容量向上取整为2的幂
+func newRing(size int) *ring {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	r := &ring{mask: uint64(n - 1), slots: make([]ringSlot, n)}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// push 缓冲区满时返回false
+func (r *ring) push(item batchItem) bool {
+	pos := r.tail.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		seq := slot.seq.Load()
+		switch diff := int64(seq - pos); {
+		case diff == 0:
+			if r.tail.CompareAndSwap(pos, pos+1) {
+				slot.item = item
+				slot.seq.Store(pos + 1)
+				return true
+			}
+			pos = r.tail.Load()
+		case diff < 0:
+			return false
+		default:
+			pos = r.tail.Load()
+		}
+	}
+}
+
+// pop 缓冲区空时返回false
+func (r *ring) pop() (batchItem, bool) {
+	pos := r.head.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		seq := slot.seq.Load()
+		switch diff := int64(seq - (pos + 1)); {
+		case diff == 0:
+			if r.head.CompareAndSwap(pos, pos+1) {
+				item := slot.item
+				slot.item = batchItem{}
+				slot.seq.Store(pos + r.mask + 1)
+				return item, true
+			}
+			pos = r.head.Load()
+		case diff < 0:
+			return batchItem{}, false
+		default:
+			pos = r.head.Load()
+		}
+	}
+}
+
+// len 近似长度
+func (r *ring) len() int {
+	n := int64(r.tail.Load() - r.head.Load())
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// cap
+func (r *ring) cap() int {
+	return len(r.slots)
 }
 
 // WithBatch 包装logger以支持批处理
 type BatchWrapper struct {
 	*BatchLogger
-	original *zap.Logger
-}
-
-// With 实现StdLogger接口
-func (bw *BatchWrapper) With(fields ...zap.Field) *zap.Logger {
-	return bw.original.With(fields...)
 }
 
 // NewBatchWrapper 创建批处理包装器
 func NewBatchWrapper(logger *zap.Logger, config BatchConfig) *BatchWrapper {
-	batchLogger := NewBatchLogger(logger, config)
-	return &BatchWrapper{
-		BatchLogger: batchLogger,
-		original:    logger,
-	}
+	return &BatchWrapper{BatchLogger: NewBatchLogger(logger, config)}
 }
 
 // 全局批处理日志器实例
@@ -238,7 +464,7 @@ var (
 // EnableBatchLogging 启用全局批处理日志
 func EnableBatchLogging(config BatchConfig) {
 	batchOnce.Do(func() {
-		globalBatchLogger = NewBatchLogger(std, config)
+		globalBatchLogger = NewBatchLogger(std(), config)
 	})
 }
 
@@ -247,7 +473,7 @@ func BatchDebug(msg string, fields ...zap.Field) {
 	if globalBatchLogger != nil {
 		globalBatchLogger.Debug(msg, fields...)
 	} else {
-		std.Debug(msg, fields...)
+		std().Debug(msg, fields...)
 	}
 }
 
@@ -256,7 +482,7 @@ func BatchInfo(msg string, fields ...zap.Field) {
 	if globalBatchLogger != nil {
 		globalBatchLogger.Info(msg, fields...)
 	} else {
-		std.Info(msg, fields...)
+		std().Info(msg, fields...)
 	}
 }
 
@@ -265,7 +491,7 @@ func BatchWarn(msg string, fields ...zap.Field) {
 	if globalBatchLogger != nil {
 		globalBatchLogger.Warn(msg, fields...)
 	} else {
-		std.Warn(msg, fields...)
+		std().Warn(msg, fields...)
 	}
 }
 
@@ -274,7 +500,7 @@ func BatchError(msg string, fields ...zap.Field) {
 	if globalBatchLogger != nil {
 		globalBatchLogger.Error(msg, fields...)
 	} else {
-		std.Error(msg, fields...)
+		std().Error(msg, fields...)
 	}
 }
 
@@ -1,12 +1,16 @@
 package logger
 
 import (
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestBatchLogger(t *testing.T) {
@@ -142,6 +146,104 @@ func TestBatchLoggerBufferOverflow(t *testing.T) {
 	}
 }
 
+func TestBatchLoggerOverflowPolicies(t *testing.T) {
+	messages := func(logs *observer.ObservedLogs) []string {
+		var ms []string
+		for _, e := range logs.All() {
+			ms = append(ms, e.Message)
+		}
+		return ms
+	}
+	tests := []struct {
+		policy OverflowPolicy
+		want   string
+	}{
+		{OverflowDropNewest, "0,1,2,3"},
+		{OverflowDropOldest, "2,3,4,5"},
+		{OverflowSpill, "4,5,0,1,2,3"},
+	}
+	for _, tt := range tests {
+		core, logs := observer.New(zapcore.DebugLevel)
+		bl := NewBatchLogger(zap.New(core), BatchConfig{
+			FlushSize: 100, FlushInterval: time.Hour, BufferSize: 4, Overflow: tt.policy,
+		})
+		for _, m := range []string{"0", "1", "2", "3", "4", "5"} {
+			bl.Info(m)
+		}
+		bl.Flush()
+		if got := strings.Join(messages(logs), ","); got != tt.want {
+			t.Errorf("policy %d: got %s, want %s", tt.policy, got, tt.want)
+		}
+		_ = bl.Close()
+	}
+}
+
+func TestBatchLoggerBlock(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	bl := NewBatchLogger(zap.New(core), BatchConfig{
+		FlushSize: 100, FlushInterval: time.Hour, BufferSize: 2,
+		Overflow: OverflowBlock, BlockTimeout: time.Second,
+	})
+	defer bl.Close()
+
+	// the full buffer kicks the flusher, so the blocked writes get the space
+	for i := 0; i < 10; i++ {
+		bl.Info("blocked", zap.Int("i", i))
+	}
+	bl.Flush()
+	stats := bl.Stats()
+	if logs.Len() != 10 || stats.DroppedLogs != 0 || stats.BlockedLogs == 0 {
+		t.Errorf("expect all logs written after blocking, got %d logs, stats %+v", logs.Len(), stats)
+	}
+}
+
+func TestBatchLoggerWithAndError(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	bl := NewBatchLogger(zap.New(core), BatchConfig{FlushSize: 100, FlushInterval: time.Hour})
+	defer bl.Close()
+
+	child := bl.With(zap.String("component", "test"))
+	child.Info("batched")
+	if logs.Len() != 0 {
+		t.Fatal("expect the child logger to be batched")
+	}
+	bl.Error("failed")
+	all := logs.All()
+	if len(all) != 2 || all[0].Message != "batched" || all[1].Message != "failed" {
+		t.Fatalf("expect the pending logs flushed before the error, got %v", all)
+	}
+	if all[0].ContextMap()["component"] != "test" {
+		t.Errorf("expect the with fields, got %v", all[0].ContextMap())
+	}
+}
+
+func TestBatchLoggerMetrics(t *testing.T) {
+	core, _ := observer.New(zapcore.DebugLevel)
+	bl := NewBatchLogger(zap.New(core), BatchConfig{
+		Name: "metrics_test", FlushSize: 100, FlushInterval: time.Hour, BufferSize: 2,
+	})
+	for i := 0; i < 3; i++ {
+		bl.Info("metrics")
+	}
+	bl.Flush()
+	expected := `
+# HELP ginny_log_batch_flushes_total Total number of batch logger flushes.
+# TYPE ginny_log_batch_flushes_total counter
+ginny_log_batch_flushes_total{name="metrics_test"} 1
+`
+	if err := testutil.CollectAndCompare(batchCollector{}, strings.NewReader(expected),
+		"ginny_log_batch_flushes_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(batchCollector{}, "ginny_log_batch_entries_total"); n < 4 {
+		t.Errorf("expect the entries by result, got %d", n)
+	}
+	_ = bl.Close()
+	if n := testutil.CollectAndCount(batchCollector{}, "ginny_log_batch_pending"); n != 0 {
+		t.Errorf("expect the closed logger unregistered, got %d", n)
+	}
+}
+
 func TestBatchWrapper(t *testing.T) {
 	baseLogger := zaptest.NewLogger(t)
 	config := BatchConfig{
@@ -0,0 +1,316 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/goriller/ginny-util/graceful"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// LoggerProviderSet provide the logger built from the `log` section of config, it is not
+// in ginny.AppProviderSet which provides the default logger.
+var LoggerProviderSet = wire.NewSet(NewFromConfig)
+
+const (
+	// SinkStdout write to stdout
+	SinkStdout = "stdout"
+	// SinkStderr write to stderr
+	SinkStderr = "stderr"
+	// SinkFile write to the rotated file
+	SinkFile = "file"
+	// SinkSyslog write to the syslog server
+	SinkSyslog = "syslog"
+
+	// EncodingJSON the json encoder
+	EncodingJSON = "json"
+	// EncodingConsole the human readable console encoder
+	EncodingConsole = "console"
+)
+
+// Config the config of logger
+type Config struct {
+	// Level the global level, debug by default
+	Level string
+	// Development use the development encoder config, console encoding, stacktrace from warn,
+	// and panic on DPanic.
+	Development bool
+	// Encoding json or console, json by default, console in development mode
+	Encoding string
+	// Sinks the outputs, stdout by default
+	Sinks []SinkConfig
+	// Sampling sample the entries by level and message
+	Sampling *SamplingConfig
+	// Dedup collapse the repeated entries in the window into one with the `repeated` count
+	Dedup time.Duration
+	// DisableCaller stop annotating the entries with the caller
+	DisableCaller bool
+	// StacktraceLevel record the stacktrace of the entries at or above the level, warn in development mode
+	StacktraceLevel string
+	// Fields the fields added to all the entries, such as service name
+	Fields map[string]string
+}
+
+// SinkConfig the config of output
+type SinkConfig struct {
+	// Type stdout, stderr, file or syslog
+	Type string
+	// Level the min level of the sink, such as only error to syslog
+	Level string
+	// Encoding override the encoding of logger
+	Encoding string
+
+	// Path the file path of file sink
+	Path string
+	// MaxSize the megabytes before rotating, 1024 by default
+	MaxSize int
+	// MaxBackups the number of rotated files to retain, 3 by default
+	MaxBackups int
+	// MaxAge the days to retain the rotated files, 3 by default
+	MaxAge int
+	// Compress gzip the rotated files
+	Compress bool
+	// LocalTime use local time in the rotated file names, UTC by default
+	LocalTime bool
+
+	// Network the network of syslog sink: udp, tcp, unix or unixgram, udp by default
+	Network string
+	// Address the address of syslog server, such as localhost:514 or /dev/log
+	Address string
+	// Tag the syslog tag, the process name by default
+	Tag string
+	// Facility the syslog facility, such as local0, user by default
+	Facility string
+}
+
+// SamplingConfig log the first Initial entries with the same level and message in each Tick,
+// then every Thereafter-th entry. The rules of Messages and Levels override the default.
+type SamplingConfig struct {
+	Tick       time.Duration
+	Initial    int
+	Thereafter int
+	// Levels the rules by level name, such as `debug`
+	Levels map[string]SamplingRule
+	// Messages the rules by message prefix, checked in order
+	Messages []MessageSamplingRule
+}
+
+// envConfig the config from env LOG_PATH and LOG_LEVEL, which is the default logger.
+func envConfig() *Config {
+	c := &Config{Level: os.Getenv("LOG_LEVEL")}
+	// you can use `export LOG_PATH=logs/log.log` to set log output to a file
+	if path := os.Getenv("LOG_PATH"); path != "" {
+		c.Sinks = []SinkConfig{{Type: SinkFile, Path: path}}
+	}
+	return c
+}
+
+// New new logger by the config, such as an audit logger, the logger has its own level which is the config level,
+// so building it doesn't change the default logger. Init makes it the default logger with the global level,
+// which can be changed at runtime with SetLevel and the named levels apply to its Named sub loggers too.
+func New(c *Config) (*zap.Logger, error) {
+	return build(c, zap.NewAtomicLevelAt(zapcore.DebugLevel))
+}
+
+// build the logger filtered by the level, which is set to the config level if the logger is built.
+func build(c *Config, lvl zap.AtomicLevel) (*zap.Logger, error) {
+	if c == nil {
+		c = &Config{}
+	}
+	configLevel := lvl.Level()
+	if c.Level != "" {
+		if err := configLevel.UnmarshalText([]byte(c.Level)); err != nil {
+			return nil, errors.Wrap(err, "invalid log level")
+		}
+	}
+
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoding := EncodingJSON
+	if c.Development {
+		encoderCfg = zap.NewDevelopmentEncoderConfig()
+		encoding = EncodingConsole
+	}
+	encoderCfg.TimeKey = "time"
+	encoderCfg.CallerKey = "caller"
+	encoderCfg.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	if c.Encoding != "" {
+		encoding = c.Encoding
+	}
+
+	sinks := c.Sinks
+	if len(sinks) == 0 {
+		sinks = []SinkConfig{{Type: SinkStdout}}
+	}
+	cores := make([]zapcore.Core, 0, len(sinks))
+	for i := range sinks {
+		core, err := newSinkCore(&sinks[i], encoderCfg, encoding)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, core)
+	}
+
+	var core zapcore.Core = zapcore.NewTee(cores...)
+	if c.Dedup > 0 {
+		core = NewDedupCore(core, c.Dedup)
+	}
+	if s := c.Sampling; s != nil && (s.Initial > 0 || len(s.Levels) > 0 || len(s.Messages) > 0) {
+		sc, err := NewSamplerCore(core, s)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid sampling level")
+		}
+		core = sc
+	}
+	// the sinks accept all, the levels are checked by the level of the logger or the named level
+	core = &levelCore{Core: core, level: lvl}
+
+	opts := []zap.Option{zap.AddCallerSkip(1)}
+	if !c.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+	if c.StacktraceLevel != "" {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(c.StacktraceLevel)); err != nil {
+			return nil, errors.Wrap(err, "invalid stacktrace level")
+		}
+		opts = append(opts, zap.AddStacktrace(l))
+	} else if c.Development {
+		opts = append(opts, zap.AddStacktrace(zapcore.WarnLevel))
+	}
+	if c.Development {
+		opts = append(opts, zap.Development())
+	}
+	if len(c.Fields) > 0 {
+		fields := make([]zap.Field, 0, len(c.Fields))
+		for k, v := range c.Fields {
+			fields = append(fields, zap.String(k, v))
+		}
+		opts = append(opts, zap.Fields(fields...))
+	}
+	lvl.SetLevel(configLevel)
+	return zap.New(core, opts...), nil
+}
+
+// newSinkCore
+func newSinkCore(s *SinkConfig, encoderCfg zapcore.EncoderConfig, encoding string) (zapcore.Core, error) {
+	enabler := zapcore.DebugLevel
+	if s.Level != "" {
+		if err := enabler.UnmarshalText([]byte(s.Level)); err != nil {
+			return nil, errors.Wrapf(err, "invalid level of %s sink", s.Type)
+		}
+	}
+	if s.Encoding != "" {
+		encoding = s.Encoding
+	}
+	var enc zapcore.Encoder
+	switch encoding {
+	case EncodingJSON:
+		enc = zapcore.NewJSONEncoder(encoderCfg)
+	case EncodingConsole:
+		enc = zapcore.NewConsoleEncoder(encoderCfg)
+	default:
+		return nil, fmt.Errorf("unknown log encoding %q", encoding)
+	}
+
+	switch strings.ToLower(s.Type) {
+	case SinkStdout, "":
+		return zapcore.NewCore(enc, redactWriter{zapcore.Lock(os.Stdout)}, enabler), nil
+	case SinkStderr:
+		return zapcore.NewCore(enc, redactWriter{zapcore.Lock(os.Stderr)}, enabler), nil
+	case SinkFile:
+		if s.Path == "" {
+			return nil, errors.New("the path of file sink is required")
+		}
+		fw := &lumberjack.Logger{
+			Filename:   s.Path,
+			MaxSize:    orDefault(s.MaxSize, 1024), // megabytes
+			MaxBackups: orDefault(s.MaxBackups, 3),
+			MaxAge:     orDefault(s.MaxAge, 3), // days
+			Compress:   s.Compress,
+			LocalTime:  s.LocalTime,
+		}
+		return zapcore.NewCore(enc, redactWriter{zapcore.AddSync(fw)}, enabler), nil
+	case SinkSyslog:
+		return newSyslogCore(s, enc, enabler)
+	default:
+		return nil, fmt.Errorf("unknown log sink %q", s.Type)
+	}
+}
+
+// orDefault
+func orDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// Init replace the default logger and the zap globals by the logger built from the config,
+// the global level is set to the config level.
+func Init(c *Config) error {
+	l, err := build(c, level)
+	if err != nil {
+		return err
+	}
+	setDefault(l)
+	return nil
+}
+
+// syncOnce register the closer to sync the default logger once
+var syncOnce sync.Once
+
+// setDefault
+func setDefault(l *zap.Logger) {
+	stdLogger.Store(l)
+	zap.ReplaceGlobals(l)
+	syncOnce.Do(func() {
+		graceful.AddCloser(func(ctx context.Context) error {
+			return std().Sync()
+		})
+	})
+}
+
+// NewFromConfig build a logger with its own level from the `log` section of config if set,
+// otherwise return the default logger. The default logger and the zap globals are not
+// changed, see InitFromConfig.
+//
+//	log:
+//	  level: info
+//	  encoding: json
+//	  sinks:
+//	    - type: stdout
+//	    - type: file
+//	      path: logs/app.log
+//	      maxSize: 100
+func NewFromConfig(v *viper.Viper) (*zap.Logger, error) {
+	if !v.IsSet("log") {
+		return std(), nil
+	}
+	c := new(Config)
+	if err := v.UnmarshalKey("log", c); err != nil {
+		return nil, errors.Wrap(err, "unmarshal log config error")
+	}
+	return New(c)
+}
+
+// InitFromConfig replace the default logger and the zap globals by Init with the `log` section
+// of config if set, otherwise the default logger from env LOG_PATH and LOG_LEVEL is kept.
+func InitFromConfig(v *viper.Viper) error {
+	if !v.IsSet("log") {
+		return nil
+	}
+	c := new(Config)
+	if err := v.UnmarshalKey("log", c); err != nil {
+		return errors.Wrap(err, "unmarshal log config error")
+	}
+	return Init(c)
+}
@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew(t *testing.T) {
+	oldLevel := level.Level()
+	defer level.SetLevel(oldLevel)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := New(&Config{
+		Level:    "info",
+		Encoding: EncodingConsole,
+		Fields:   map[string]string{"service": "demo"},
+		Sinks: []SinkConfig{
+			{Type: SinkFile, Path: path, MaxSize: 1, Encoding: EncodingJSON},
+			{Type: SinkSyslog, Address: pc.LocalAddr().String(), Tag: "demo", Facility: "local0", Level: "error"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.Level() != oldLevel {
+		t.Errorf("expected the global level unchanged, got %s", level.Level())
+	}
+	// the logger has its own level
+	level.SetLevel(zapcore.ErrorLevel)
+	l.Debug("hidden")
+	l.Info("hello")
+	l.Error("failed")
+	_ = l.Sync()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"msg":"hello"`) || !strings.Contains(lines[0], `"service":"demo"`) {
+		t.Errorf("unexpected file output %q", data)
+	}
+
+	buf := make([]byte, 1024)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local0*8 + error
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<131>") || !strings.Contains(msg, "demo[") || !strings.Contains(msg, "failed") {
+		t.Errorf("unexpected syslog message %q", msg)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, c := range []*Config{
+		{Level: "loud"},
+		{Encoding: "xml"},
+		{Sinks: []SinkConfig{{Type: "kafka"}}},
+		{Sinks: []SinkConfig{{Type: SinkFile}}},
+		{Sinks: []SinkConfig{{Type: SinkSyslog, Address: "localhost:514", Facility: "nope"}}},
+	} {
+		if _, err := New(c); err == nil {
+			t.Errorf("config %+v should be invalid", c)
+		}
+	}
+}
+
+func TestInitConcurrentDefault(t *testing.T) {
+	old, oldLevel := std(), level.Level()
+	defer func() {
+		setDefault(old)
+		level.SetLevel(oldLevel)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = FromContext(context.Background())
+			Default().Debug("concurrent")
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if err := Init(&Config{Level: "error", Sinks: []SinkConfig{{Type: SinkStderr}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	if level.Level() != zapcore.ErrorLevel {
+		t.Errorf("expected Init to set the global level, got %s", level.Level())
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	old, oldLevel := std(), level.Level()
+	defer func() {
+		setDefault(old)
+		level.SetLevel(oldLevel)
+	}()
+
+	v := viper.New()
+	if l, err := NewFromConfig(v); err != nil || l != old {
+		t.Fatalf("expected the default logger without log config, got %v", err)
+	}
+
+	v.Set("log", map[string]interface{}{
+		"level":    "warn",
+		"sampling": map[string]interface{}{"tick": "1s", "initial": 10, "thereafter": 100},
+		"sinks":    []map[string]interface{}{{"type": "stderr", "encoding": "console"}},
+	})
+	l, err := NewFromConfig(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l == old || Default() != old || zap.L() == l || level.Level() != oldLevel {
+		t.Error("expected the globals unchanged by NewFromConfig")
+	}
+	if l.Core().Enabled(zapcore.InfoLevel) || !l.Core().Enabled(zapcore.WarnLevel) {
+		t.Error("expected the config level")
+	}
+
+	if err := InitFromConfig(v); err != nil {
+		t.Fatal(err)
+	}
+	if Default() == old || zap.L() != Default() || level.Level() != zapcore.WarnLevel {
+		t.Error("expected the default logger replaced by InitFromConfig")
+	}
+}
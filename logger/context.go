@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+)
+
+const (
+	traceIDKey = "trace_id"
+	spanIDKey  = "span_id"
+)
+
+// FromContext return the request scoped logger stored by SetContextLogger, which is set by the server
+// interceptors and the gateway middleware, or the default logger with the fields of the context.
+// A stored StdLogger other than *zap.Logger is converted by its With.
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return std()
+	}
+	switch l := ctx.Value(loggerKey{}).(type) {
+	case *zap.Logger:
+		return l
+	case StdLogger:
+		return l.With()
+	}
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return std()
+	}
+	return std().With(fields...)
+}
+
+// ContextFields return the fields of the context: all the tags, such as request_id,
+// and the trace_id and span_id of the span if it is traced.
+func ContextFields(ctx context.Context) []zap.Field {
+	return appendContextFields(nil, ctx)
+}
+
+// appendContextFields
+func appendContextFields(fields []zap.Field, ctx context.Context) []zap.Field {
+	for k, v := range tags.Extract(ctx).Values() {
+		fields = append(fields, zap.Any(k, v))
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		traceID, spanID := spanIDs(span)
+		if traceID != "" {
+			fields = append(fields, zap.String(traceIDKey, traceID))
+		}
+		if spanID != "" {
+			fields = append(fields, zap.String(spanIDKey, spanID))
+		}
+	}
+	return fields
+}
+
+// spanIDs the trace and span id of the span, which are read from the propagation format of the tracer,
+// such as `uber-trace-id` of jaeger, `x-b3-traceid` of zipkin and `traceparent` of w3c.
+func spanIDs(span opentracing.Span) (traceID, spanID string) {
+	carrier := opentracing.TextMapCarrier{}
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		return "", ""
+	}
+	for k, v := range carrier {
+		k = strings.ToLower(k)
+		switch {
+		case k == "uber-trace-id":
+			// {trace-id}:{span-id}:{parent-span-id}:{flags}
+			if s, err := url.QueryUnescape(v); err == nil {
+				v = s
+			}
+			if parts := strings.Split(v, ":"); len(parts) == 4 {
+				return parts[0], parts[1]
+			}
+		case k == "traceparent":
+			// {version}-{trace-id}-{parent-id}-{flags}
+			if parts := strings.Split(v, "-"); len(parts) == 4 {
+				return parts[1], parts[2]
+			}
+		case strings.HasSuffix(k, "traceid"):
+			traceID = v
+		case strings.HasSuffix(k, "spanid") && !strings.HasSuffix(k, "parentspanid"):
+			spanID = v
+		}
+	}
+	return traceID, spanID
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	stdLogger.Store(zap.New(core))
+	defer func() { stdLogger.Store(zap.NewNop()) }()
+
+	if FromContext(context.Background()) != std() {
+		t.Error("expect the default logger without fields")
+	}
+
+	ctx := tags.InjectIntoContext(context.Background(), tags.NewTags())
+	tags.Extract(ctx).Set("request_id", "req-1")
+	tags.Extract(ctx).Set("user_id", int64(42))
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("test")
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	FromContext(ctx).Info("hello")
+	fields := logs.All()[0].ContextMap()
+	mspan := span.(*mocktracer.MockSpan)
+	if fields["request_id"] != "req-1" || fields["user_id"] != int64(42) {
+		t.Errorf("unexpected tags %v", fields)
+	}
+	if fields[traceIDKey] == "" || fields[traceIDKey] != strconv.Itoa(mspan.SpanContext.TraceID) ||
+		fields[spanIDKey] != strconv.Itoa(mspan.SpanContext.SpanID) {
+		t.Errorf("unexpected trace ids %v", fields)
+	}
+
+	stored := std().With(zap.String("scope", "request"))
+	if FromContext(SetContextLogger(ctx, stored)) != stored {
+		t.Error("expect the stored logger")
+	}
+
+	// any StdLogger stored by SetContextLogger is honored
+	wrapped := wrappedLogger{std().With(zap.String("scope", "wrapped"))}
+	FromContext(SetContextLogger(ctx, wrapped)).Info("wrapped")
+	if entries := logs.FilterMessage("wrapped").All(); len(entries) != 1 ||
+		entries[0].ContextMap()["scope"] != "wrapped" {
+		t.Errorf("expect the stored StdLogger, got %v", entries)
+	}
+}
+
+// wrappedLogger a StdLogger which is not a *zap.Logger
+type wrappedLogger struct {
+	*zap.Logger
+}
+
+func TestSpanIDs(t *testing.T) {
+	tests := []struct {
+		carrier opentracing.TextMapCarrier
+		trace   string
+		span    string
+	}{
+		{opentracing.TextMapCarrier{"uber-trace-id": "abc%3Adef%3A0%3A1"}, "abc", "def"},
+		{opentracing.TextMapCarrier{"traceparent": "00-abc-def-01"}, "abc", "def"},
+		{opentracing.TextMapCarrier{"X-B3-TraceId": "abc", "X-B3-SpanId": "def", "X-B3-ParentSpanId": "x"}, "abc", "def"},
+	}
+	for _, tt := range tests {
+		noop := opentracing.NoopTracer{}.StartSpan("test")
+		trace, span := spanIDs(carrierSpan{Span: noop, tracer: carrierTracer{carrier: tt.carrier}})
+		if trace != tt.trace || span != tt.span {
+			t.Errorf("spanIDs(%v) = %s, %s", tt.carrier, trace, span)
+		}
+	}
+}
+
+// carrierTracer inject the fixed carrier
+type carrierTracer struct {
+	opentracing.NoopTracer
+	carrier opentracing.TextMapCarrier
+}
+
+func (t carrierTracer) Inject(_ opentracing.SpanContext, _ interface{}, carrier interface{}) error {
+	for k, v := range t.carrier {
+		carrier.(opentracing.TextMapCarrier).Set(k, v)
+	}
+	return nil
+}
+
+// carrierSpan
+type carrierSpan struct {
+	opentracing.Span
+	tracer carrierTracer
+}
+
+func (s carrierSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
@@ -0,0 +1,323 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels the per-name levels, the global level is `level`
+var levels = &levelRegistry{overrides: map[string]*override{}}
+
+func init() {
+	levels.snapshot.Store(&map[string]zapcore.Level{})
+}
+
+// override the level overridden at runtime
+type override struct {
+	level   zapcore.Level
+	set     bool // false if the override reverts to the global level
+	expires time.Time
+	timer   *time.Timer
+	// prev the state before the temporary change, restored on expiry
+	prevLevel zapcore.Level
+	prevSet   bool
+}
+
+// levelRegistry the registry of the per-name levels,
+// the loggers read the copy-on-write snapshot without locking.
+type levelRegistry struct {
+	mu        sync.Mutex
+	overrides map[string]*override
+	global    *override
+	snapshot  atomic.Pointer[map[string]zapcore.Level]
+}
+
+// enabled look up the level by the name, then its parents `a.b` -> `a`, then the level of the logger.
+func (r *levelRegistry) enabled(name string, lvl zap.AtomicLevel, l zapcore.Level) bool {
+	m := *r.snapshot.Load()
+	if len(m) > 0 {
+		for n := name; n != ""; {
+			if lvl, ok := m[n]; ok {
+				return l >= lvl
+			}
+			i := strings.LastIndexByte(n, '.')
+			if i < 0 {
+				break
+			}
+			n = n[:i]
+		}
+	}
+	return lvl.Enabled(l)
+}
+
+// publish store the snapshot of the overrides, the caller must hold the lock.
+func (r *levelRegistry) publish() {
+	m := make(map[string]zapcore.Level, len(r.overrides))
+	for name, o := range r.overrides {
+		if o.set {
+			m[name] = o.level
+		}
+	}
+	r.snapshot.Store(&m)
+}
+
+// set set the level of the name, or the global level if the name is empty.
+func (r *levelRegistry) set(name string, l zapcore.Level, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o := r.get(name)
+	if o.timer != nil {
+		// keep the state before the first temporary change
+		o.timer.Stop()
+		o.timer = nil
+	} else {
+		o.prevLevel, o.prevSet = o.level, o.set
+		if name == "" {
+			o.prevLevel = level.Level()
+		}
+	}
+	o.level, o.set, o.expires = l, true, time.Time{}
+	if ttl > 0 {
+		o.expires = time.Now().Add(ttl)
+		o.timer = time.AfterFunc(ttl, func() {
+			r.revert(name, o)
+		})
+	}
+	r.apply(name, o)
+}
+
+// reset remove the override of the name, and cancel the pending revert.
+func (r *levelRegistry) reset(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.overrides[name]
+	if !ok {
+		return
+	}
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+	delete(r.overrides, name)
+	r.publish()
+}
+
+// revert restore the state before the temporary change.
+func (r *levelRegistry) revert(name string, o *override) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cur := r.lookup(name); cur != o || o.timer == nil {
+		// changed or reset since
+		return
+	}
+	o.timer = nil
+	o.level, o.set, o.expires = o.prevLevel, o.prevSet, time.Time{}
+	r.apply(name, o)
+}
+
+// get return the override of the name, created if absent, the caller must hold the lock.
+func (r *levelRegistry) get(name string) *override {
+	if o := r.lookup(name); o != nil {
+		return o
+	}
+	o := &override{}
+	if name == "" {
+		r.global = o
+	} else {
+		r.overrides[name] = o
+	}
+	return o
+}
+
+// lookup the caller must hold the lock.
+func (r *levelRegistry) lookup(name string) *override {
+	if name == "" {
+		return r.global
+	}
+	return r.overrides[name]
+}
+
+// apply the caller must hold the lock.
+func (r *levelRegistry) apply(name string, o *override) {
+	if name == "" {
+		level.SetLevel(o.level)
+		return
+	}
+	if !o.set {
+		delete(r.overrides, name)
+	}
+	r.publish()
+}
+
+// LevelState the level state returned by the level handler
+type LevelState struct {
+	Level   string               `json:"level"`
+	Expires *time.Time           `json:"expires,omitempty"`
+	Loggers map[string]NameLevel `json:"loggers,omitempty"`
+}
+
+// NameLevel the level of the named logger
+type NameLevel struct {
+	Level   string     `json:"level"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// state
+func (r *levelRegistry) state() *LevelState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &LevelState{Level: level.Level().String()}
+	if r.global != nil && !r.global.expires.IsZero() {
+		t := r.global.expires
+		s.Expires = &t
+	}
+	if len(r.overrides) > 0 {
+		s.Loggers = make(map[string]NameLevel, len(r.overrides))
+		for name, o := range r.overrides {
+			nl := NameLevel{Level: o.level.String()}
+			if !o.expires.IsZero() {
+				t := o.expires
+				nl.Expires = &t
+			}
+			s.Loggers[name] = nl
+		}
+	}
+	return s
+}
+
+// levelCore filter the entries by the level of the name, or the level of the logger
+type levelCore struct {
+	zapcore.Core
+	name  string
+	level zap.AtomicLevel
+}
+
+// Enabled implement zapcore.LevelEnabler
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return levels.enabled(c.name, c.level, l)
+}
+
+// With implement zapcore.Core
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), name: c.name, level: c.level}
+}
+
+// Check implement zapcore.Core
+func (c *levelCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(e.Level) {
+		return ce
+	}
+	return c.Core.Check(e, ce)
+}
+
+// Named return the named sub logger of the default logger, its level can be changed by SetNamedLevel
+// independently, it falls back to the level of its parent name `a` for `a.b`, then the global level.
+func Named(name string) *zap.Logger {
+	return std().WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		lvl := level
+		if lc, ok := c.(*levelCore); ok {
+			c, lvl = lc.Core, lc.level
+		}
+		return &levelCore{Core: c, name: name, level: lvl}
+	})).Named(name)
+}
+
+// SetLevel set the global level, it reverts to the previous level after the ttl if the ttl > 0.
+func SetLevel(l zapcore.Level, ttl time.Duration) {
+	levels.set("", l, ttl)
+}
+
+// SetNamedLevel set the level of the named logger, it reverts to the previous level after the ttl if the ttl > 0.
+func SetNamedLevel(name string, l zapcore.Level, ttl time.Duration) {
+	levels.set(name, l, ttl)
+}
+
+// ResetNamedLevel remove the level of the named logger, it falls back to the parent or global level.
+func ResetNamedLevel(name string) {
+	if name != "" {
+		levels.reset(name)
+	}
+}
+
+// Levels return the global level and the levels of the named loggers.
+func Levels() *LevelState {
+	return levels.state()
+}
+
+// levelRequest the request of level handler
+type levelRequest struct {
+	Level  string `json:"level"`
+	Logger string `json:"logger"`
+	TTL    string `json:"ttl"`
+}
+
+// LevelHandler the http handler to change the levels at runtime:
+//
+//	GET                                                    the levels
+//	PUT {"level":"debug"}                                  set the global level
+//	PUT {"level":"debug","logger":"cache","ttl":"10m"}     set the level of the named logger for 10 minutes
+//	DELETE ?logger=cache                                   reset the level of the named logger
+//
+// The fields can be query parameters too.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			req, err := decodeLevelRequest(r)
+			if err != nil {
+				writeLevelError(w, http.StatusBadRequest, err)
+				return
+			}
+			var l zapcore.Level
+			if err = l.UnmarshalText([]byte(req.Level)); err != nil || req.Level == "" {
+				writeLevelError(w, http.StatusBadRequest, fmt.Errorf("invalid level %q", req.Level))
+				return
+			}
+			var ttl time.Duration
+			if req.TTL != "" {
+				if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+					writeLevelError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q", req.TTL))
+					return
+				}
+			}
+			levels.set(req.Logger, l, ttl)
+		case http.MethodDelete:
+			ResetNamedLevel(r.URL.Query().Get("logger"))
+		default:
+			writeLevelError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levels.state())
+	})
+}
+
+// decodeLevelRequest
+func decodeLevelRequest(r *http.Request) (*levelRequest, error) {
+	q := r.URL.Query()
+	req := &levelRequest{Level: q.Get("level"), Logger: q.Get("logger"), TTL: q.Get("ttl")}
+	if r.Body == nil || r.ContentLength == 0 {
+		return req, nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid body: %w", err)
+	}
+	return req, nil
+}
+
+// writeLevelError
+func writeLevelError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNamedLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	old, oldLevel := std(), level.Level()
+	stdLogger.Store(zap.New(&levelCore{Core: core, level: level}))
+	defer func() {
+		stdLogger.Store(old)
+		level.SetLevel(oldLevel)
+		ResetNamedLevel("cache")
+	}()
+	level.SetLevel(zapcore.InfoLevel)
+
+	cache := Named("cache")
+	redis := Named("cache.redis")
+	other := Named("db")
+
+	SetNamedLevel("cache", zapcore.DebugLevel, 0)
+	cache.Debug("cache debug")
+	redis.Debug("redis debug")
+	other.Debug("db debug")
+	std().Debug("std debug")
+	if got := logs.TakeAll(); len(got) != 2 || got[0].LoggerName != "cache" || got[1].LoggerName != "cache.redis" {
+		t.Fatalf("expected cache and its child to log debug, got %v", got)
+	}
+
+	SetNamedLevel("cache", zapcore.ErrorLevel, 0)
+	cache.With(zap.String("k", "v")).Warn("cache warn")
+	other.Info("db info")
+	if got := logs.TakeAll(); len(got) != 1 || got[0].LoggerName != "db" {
+		t.Fatalf("expected only db to log, got %v", got)
+	}
+
+	ResetNamedLevel("cache")
+	cache.Info("cache info")
+	if logs.Len() != 1 {
+		t.Fatalf("expected cache to fall back to the global level")
+	}
+}
+
+func TestLevelTTL(t *testing.T) {
+	oldLevel := level.Level()
+	defer level.SetLevel(oldLevel)
+	level.SetLevel(zapcore.InfoLevel)
+
+	SetLevel(zapcore.DebugLevel, 20*time.Millisecond)
+	SetNamedLevel("worker", zapcore.DebugLevel, 20*time.Millisecond)
+	if s := Levels(); s.Level != "debug" || s.Expires == nil || s.Loggers["worker"].Level != "debug" {
+		t.Fatalf("unexpected levels %+v", s)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if s := Levels(); s.Level == "info" && len(s.Loggers) == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("levels not reverted: %+v", Levels())
+}
+
+func TestLevelHandler(t *testing.T) {
+	oldLevel := level.Level()
+	defer func() {
+		level.SetLevel(oldLevel)
+		ResetNamedLevel("cache")
+	}()
+	h := LevelHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"debug","logger":"cache","ttl":"10m"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"cache":{"level":"debug"`) {
+		t.Fatalf("put named level: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/?level=warn", nil))
+	if w.Code != http.StatusOK || level.Level() != zapcore.WarnLevel {
+		t.Fatalf("put global level: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/?logger=cache", nil))
+	if strings.Contains(w.Body.String(), "cache") {
+		t.Fatalf("delete named level: %s", w.Body.String())
+	}
+
+	for _, body := range []string{`{"level":"loud"}`, `{"level":"info","ttl":"soon"}`, `{`} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
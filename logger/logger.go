@@ -4,7 +4,6 @@ import (
 	"context"
 	"sync"
 
-	"github.com/goriller/ginny/interceptor/tags"
 	"go.uber.org/zap"
 )
 
@@ -48,23 +47,23 @@ func putFields(fields []zap.Field) {
 
 // Default the default log instance
 func Default() *zap.Logger {
-	return std
+	return std()
 }
 
 // Action set action filed for logger
 func Action(action string) StdLogger {
-	return std.With(zap.String(actionKey, action))
+	return std().With(zap.String(actionKey, action))
 }
 
 // With with any map data， the value of key must be string, int ... basic value
 func With(m ...zap.Field) StdLogger {
-	return std.With(m...)
+	return std().With(m...)
 }
 
 // WithTags with map string string tags - 优化版本
 func WithTags(m map[string]string) StdLogger {
 	if len(m) == 0 {
-		return std
+		return std()
 	}
 
 	fields := getFields()
@@ -78,34 +77,24 @@ func WithTags(m map[string]string) StdLogger {
 	copyFields := make([]zap.Field, len(fields))
 	copy(copyFields, fields)
 
-	return std.With(copyFields...)
+	return std().With(copyFields...)
 }
 
-// WithContext - 优化版本，使用对象池
+// WithContext - 优化版本，使用对象池，包含所有类型的tags以及trace_id、span_id
 func WithContext(ctx context.Context) StdLogger {
-	ts := tags.Extract(ctx).Values()
-	if len(ts) == 0 {
-		return std
-	}
-
 	fields := getFields()
 	defer putFields(fields)
 
-	for k, v := range ts {
-		if strVal, ok := v.(string); ok {
-			fields = append(fields, zap.String(k, strVal))
-		}
-	}
-
+	fields = appendContextFields(fields, ctx)
 	if len(fields) == 0 {
-		return std
+		return std()
 	}
 
 	// 复制fields，避免池化对象被修改
 	copyFields := make([]zap.Field, len(fields))
 	copy(copyFields, fields)
 
-	return std.With(copyFields...)
+	return std().With(copyFields...)
 }
 
 // SetContextLogger context with tags logger
@@ -116,10 +105,10 @@ func SetContextLogger(ctx context.Context, log StdLogger) context.Context {
 // GetContextLogger extract logger from context
 func GetContextLogger(ctx context.Context) StdLogger {
 	if ctx == nil {
-		return std
+		return std()
 	}
 	if ctxLogger, ok := ctx.Value(loggerKey{}).(StdLogger); ok {
 		return ctxLogger
 	}
-	return std
+	return std()
 }
@@ -11,8 +11,8 @@ import (
 
 func TestWithTags(t *testing.T) {
 	// 使用测试logger
-	std = zaptest.NewLogger(t)
-	defer func() { std = zap.NewNop() }()
+	stdLogger.Store(zaptest.NewLogger(t))
+	defer func() { stdLogger.Store(zap.NewNop()) }()
 
 	tests := []struct {
 		name string
@@ -55,8 +55,8 @@ func TestWithTags(t *testing.T) {
 
 func TestWithContext(t *testing.T) {
 	// 使用测试logger
-	std = zaptest.NewLogger(t)
-	defer func() { std = zap.NewNop() }()
+	stdLogger.Store(zaptest.NewLogger(t))
+	defer func() { stdLogger.Store(zap.NewNop()) }()
 
 	tests := []struct {
 		name string
@@ -94,8 +94,8 @@ func TestWithContext(t *testing.T) {
 
 func TestContextLogger(t *testing.T) {
 	// 使用测试logger
-	std = zaptest.NewLogger(t)
-	defer func() { std = zap.NewNop() }()
+	stdLogger.Store(zaptest.NewLogger(t))
+	defer func() { stdLogger.Store(zap.NewNop()) }()
 
 	// 创建一个logger
 	testLogger := With(zap.String("test", "value"))
@@ -138,7 +138,7 @@ func TestObjectPoolEfficiency(t *testing.T) {
 
 // 性能基准测试 - WithTags优化前后对比
 func BenchmarkWithTags(b *testing.B) {
-	std = zap.NewNop() // 使用空logger避免I/O开销
+	stdLogger.Store(zap.NewNop()) // 使用空logger避免I/O开销
 
 	tags := map[string]string{
 		"request_id": "12345",
@@ -159,7 +159,7 @@ func BenchmarkWithTags(b *testing.B) {
 
 // 性能基准测试 - WithContext优化前后对比
 func BenchmarkWithContext(b *testing.B) {
-	std = zap.NewNop() // 使用空logger避免I/O开销
+	stdLogger.Store(zap.NewNop()) // 使用空logger避免I/O开销
 
 	// 准备包含tags的context
 	ctx := context.Background()
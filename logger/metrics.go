@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// batchers the live batch loggers, collected by name
+var batchers sync.Map
+
+var (
+	batchEntriesDesc = prometheus.NewDesc("ginny_log_batch_entries_total",
+		"Total number of batch logger entries by result.", []string{"name", "result"}, nil)
+	batchFlushesDesc = prometheus.NewDesc("ginny_log_batch_flushes_total",
+		"Total number of batch logger flushes.", []string{"name"}, nil)
+	batchBlockedDesc = prometheus.NewDesc("ginny_log_batch_blocked_total",
+		"Total number of batch logger entries which waited for the buffer space.", []string{"name"}, nil)
+	batchPendingDesc = prometheus.NewDesc("ginny_log_batch_pending",
+		"Number of entries in the batch logger buffer.", []string{"name"}, nil)
+	batchCapacityDesc = prometheus.NewDesc("ginny_log_batch_capacity",
+		"Capacity of the batch logger buffer.", []string{"name"}, nil)
+)
+
+func init() {
+	prometheus.MustRegister(batchCollector{})
+}
+
+// batchCollector export the stats of the batch loggers, the loggers with the same name are summed.
+type batchCollector struct{}
+
+// Describe implement prometheus.Collector
+func (batchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- batchEntriesDesc
+	ch <- batchFlushesDesc
+	ch <- batchBlockedDesc
+	ch <- batchPendingDesc
+	ch <- batchCapacityDesc
+}
+
+// Collect implement prometheus.Collector
+func (batchCollector) Collect(ch chan<- prometheus.Metric) {
+	type total struct {
+		stats    BatchStats
+		capacity int
+	}
+	totals := map[string]*total{}
+	batchers.Range(func(key, _ interface{}) bool {
+		b := key.(*batcher)
+		t, ok := totals[b.config.Name]
+		if !ok {
+			t = &total{}
+			totals[b.config.Name] = t
+		}
+		s := b.stats()
+		t.stats.BatchedLogs += s.BatchedLogs
+		t.stats.DroppedLogs += s.DroppedLogs
+		t.stats.SpilledLogs += s.SpilledLogs
+		t.stats.TotalLogs += s.TotalLogs
+		t.stats.FlushCount += s.FlushCount
+		t.stats.BlockedLogs += s.BlockedLogs
+		t.stats.Pending += s.Pending
+		t.capacity += b.ring.cap()
+		return true
+	})
+	for name, t := range totals {
+		s := t.stats
+		ch <- prometheus.MustNewConstMetric(batchEntriesDesc, prometheus.CounterValue, float64(s.TotalLogs), name, "accepted")
+		ch <- prometheus.MustNewConstMetric(batchEntriesDesc, prometheus.CounterValue, float64(s.BatchedLogs), name, "batched")
+		ch <- prometheus.MustNewConstMetric(batchEntriesDesc, prometheus.CounterValue, float64(s.SpilledLogs), name, "spilled")
+		ch <- prometheus.MustNewConstMetric(batchEntriesDesc, prometheus.CounterValue, float64(s.DroppedLogs), name, "dropped")
+		ch <- prometheus.MustNewConstMetric(batchFlushesDesc, prometheus.CounterValue, float64(s.FlushCount), name)
+		ch <- prometheus.MustNewConstMetric(batchBlockedDesc, prometheus.CounterValue, float64(s.BlockedLogs), name)
+		ch <- prometheus.MustNewConstMetric(batchPendingDesc, prometheus.GaugeValue, float64(s.Pending), name)
+		ch <- prometheus.MustNewConstMetric(batchCapacityDesc, prometheus.GaugeValue, float64(t.capacity), name)
+	}
+}
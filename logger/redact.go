@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	redacted = "******"
+	// minSecretLen the shorter secrets are not masked, masking such as "1" or "true" in
+	// the whole output would mangle the unrelated text
+	minSecretLen = 6
+)
+
+var (
+	secretsMu sync.Mutex
+	// groups the registered secrets by group, the secrets of Redact are in group ""
+	groups = map[string][]string{}
+	// secrets holds [][]byte, replaced on every change so writers can read it without lock
+	secrets atomic.Value
+)
+
+// Redact register the secrets which will be masked in all the log output.
+// Secrets shorter than 6 bytes are ignored.
+func Redact(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	for _, v := range values {
+		if !slices.Contains(groups[""], v) {
+			groups[""] = append(groups[""], v)
+		}
+	}
+	storeSecrets()
+}
+
+// RedactGroup replace the secrets of the group which will be masked in all the log output,
+// such as the decrypted config values which are replaced on every reload.
+// Secrets shorter than 6 bytes are ignored.
+func RedactGroup(group string, values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	if len(values) == 0 {
+		delete(groups, group)
+	} else {
+		groups[group] = append([]string(nil), values...)
+	}
+	storeSecrets()
+}
+
+// storeSecrets build the secrets of all the groups, the longer first so that a secret
+// containing another one is masked as a whole
+func storeSecrets() {
+	var list [][]byte
+	for _, values := range groups {
+		for _, v := range values {
+			if len(v) < minSecretLen {
+				continue
+			}
+			list = appendSecret(list, []byte(v))
+			// the json encoder escapes the value
+			if b, err := json.Marshal(v); err == nil && len(b) > 2 {
+				list = appendSecret(list, b[1:len(b)-1])
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+	secrets.Store(list)
+}
+
+// appendSecret append if not exists
+func appendSecret(list [][]byte, s []byte) [][]byte {
+	for _, v := range list {
+		if bytes.Equal(v, s) {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// RedactBytes mask the registered secrets in b.
+func RedactBytes(b []byte) []byte {
+	list, _ := secrets.Load().([][]byte)
+	for _, s := range list {
+		if bytes.Contains(b, s) {
+			b = bytes.ReplaceAll(b, s, []byte(redacted))
+		}
+	}
+	return b
+}
+
+// redactWriter mask the registered secrets before writing.
+type redactWriter struct {
+	zapcore.WriteSyncer
+}
+
+// Write implement io.Writer
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := w.WriteSyncer.Write(RedactBytes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"testing"
+)
+
+func TestRedactGroup(t *testing.T) {
+	defer RedactGroup("test")
+
+	RedactGroup("test", "old-password", "true", "old-password-2")
+	out := string(RedactBytes([]byte(`{"a":"old-password-2","b":"old-password","c":true}`)))
+	if out != `{"a":"******","b":"******","c":true}` {
+		t.Errorf("unexpected redacted output %s", out)
+	}
+
+	// the group is replaced, the short secret is never masked
+	RedactGroup("test", "new-password")
+	out = string(RedactBytes([]byte(`old-password new-password true`)))
+	if out != `old-password ****** true` {
+		t.Errorf("unexpected redacted output %s", out)
+	}
+}
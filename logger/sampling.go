@@ -0,0 +1,357 @@
+package logger
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingRule log the first Initial entries with the same level and message in each tick,
+// then every Thereafter-th entry, all the entries are logged if Initial <= 0.
+type SamplingRule struct {
+	Initial    int
+	Thereafter int
+}
+
+// MessageSamplingRule the sampling rule of the messages with the prefix, and of the level if set.
+type MessageSamplingRule struct {
+	Message      string
+	Level        string
+	SamplingRule `mapstructure:",squash"`
+}
+
+// SamplingStats the stats of sampling and deduplication
+type SamplingStats struct {
+	Sampled    int64 `json:"sampled"`
+	Dropped    int64 `json:"dropped"`
+	Suppressed int64 `json:"suppressed"`
+	Summaries  int64 `json:"summaries"`
+}
+
+// samplingStats
+type samplingStats struct {
+	sampled    atomic.Int64
+	dropped    atomic.Int64
+	suppressed atomic.Int64
+	summaries  atomic.Int64
+}
+
+// snapshot
+func (s *samplingStats) snapshot() SamplingStats {
+	return SamplingStats{
+		Sampled:    s.sampled.Load(),
+		Dropped:    s.dropped.Load(),
+		Suppressed: s.suppressed.Load(),
+		Summaries:  s.summaries.Load(),
+	}
+}
+
+// globalSampling the stats of all the sampler and dedup cores
+var globalSampling samplingStats
+
+// GetSamplingStats return the stats of all the sampler and dedup cores.
+func GetSamplingStats() SamplingStats {
+	return globalSampling.snapshot()
+}
+
+const samplerBuckets = 4096
+
+// messageRule
+type messageRule struct {
+	prefix string
+	level  *zapcore.Level
+	rule   SamplingRule
+}
+
+// sampler the shared state of the sampler core and its With children
+type sampler struct {
+	tick     time.Duration
+	rule     SamplingRule
+	levels   map[zapcore.Level]SamplingRule
+	messages []messageRule
+	counts   [zapcore.FatalLevel - zapcore.DebugLevel + 1][samplerBuckets]counter
+	stats    samplingStats
+}
+
+// counter the count in the tick
+type counter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// inc increase and reset the count at the next tick
+func (c *counter) inc(now int64, tick time.Duration) uint64 {
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+	c.count.Store(1)
+	c.resetAt.Store(now + tick.Nanoseconds())
+	return 1
+}
+
+// SamplerCore sample the entries by level and message with the rules.
+type SamplerCore struct {
+	zapcore.Core
+	s *sampler
+}
+
+// NewSamplerCore new the sampler core, the rule of the message takes precedence over the level,
+// then the default of the config.
+func NewSamplerCore(core zapcore.Core, c *SamplingConfig) (*SamplerCore, error) {
+	s := &sampler{
+		tick:   c.Tick,
+		rule:   SamplingRule{Initial: c.Initial, Thereafter: c.Thereafter},
+		levels: make(map[zapcore.Level]SamplingRule, len(c.Levels)),
+	}
+	if s.tick <= 0 {
+		s.tick = time.Second
+	}
+	for name, r := range c.Levels {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(name)); err != nil {
+			return nil, err
+		}
+		s.levels[l] = r
+	}
+	for _, m := range c.Messages {
+		mr := messageRule{prefix: m.Message, rule: m.SamplingRule}
+		if m.Level != "" {
+			var l zapcore.Level
+			if err := l.UnmarshalText([]byte(m.Level)); err != nil {
+				return nil, err
+			}
+			mr.level = &l
+		}
+		s.messages = append(s.messages, mr)
+	}
+	return &SamplerCore{Core: core, s: s}, nil
+}
+
+// With implement zapcore.Core
+func (c *SamplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &SamplerCore{Core: c.Core.With(fields), s: c.s}
+}
+
+// Check implement zapcore.Core
+func (c *SamplerCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(e.Level) {
+		return ce
+	}
+	if !c.s.sample(e) {
+		c.s.stats.dropped.Add(1)
+		globalSampling.dropped.Add(1)
+		return ce
+	}
+	c.s.stats.sampled.Add(1)
+	globalSampling.sampled.Add(1)
+	return c.Core.Check(e, ce)
+}
+
+// Stats return the stats of the sampler.
+func (c *SamplerCore) Stats() SamplingStats {
+	return c.s.stats.snapshot()
+}
+
+// sample
+func (s *sampler) sample(e zapcore.Entry) bool {
+	if e.Level < zapcore.DebugLevel || e.Level > zapcore.FatalLevel {
+		return true
+	}
+	rule := s.ruleOf(e)
+	if rule.Initial <= 0 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(e.Message))
+	n := s.counts[e.Level-zapcore.DebugLevel][h.Sum32()%samplerBuckets].inc(e.Time.UnixNano(), s.tick)
+	if n <= uint64(rule.Initial) {
+		return true
+	}
+	return rule.Thereafter > 0 && (n-uint64(rule.Initial))%uint64(rule.Thereafter) == 0
+}
+
+// ruleOf
+func (s *sampler) ruleOf(e zapcore.Entry) SamplingRule {
+	for _, m := range s.messages {
+		if (m.level == nil || *m.level == e.Level) && strings.HasPrefix(e.Message, m.prefix) {
+			return m.rule
+		}
+	}
+	if r, ok := s.levels[e.Level]; ok {
+		return r
+	}
+	return s.rule
+}
+
+// dedupKey
+type dedupKey struct {
+	level   zapcore.Level
+	logger  string
+	message string
+}
+
+// dedupEntry the repeated entry in the window
+type dedupEntry struct {
+	first  time.Time
+	count  int64
+	entry  zapcore.Entry
+	fields []zapcore.Field
+	core   zapcore.Core
+}
+
+// deduper the shared state of the dedup core and its With children
+type deduper struct {
+	window   time.Duration
+	mu       sync.Mutex
+	entries  map[dedupKey]*dedupEntry
+	flushing bool
+	stats    samplingStats
+}
+
+// DedupCore collapse the entries with the same level, logger name and message in the window
+// into the first one and a summary with the `repeated` count of the suppressed ones.
+type DedupCore struct {
+	zapcore.Core
+	d *deduper
+}
+
+// NewDedupCore new the dedup core, the summaries are written when the window ends.
+func NewDedupCore(core zapcore.Core, window time.Duration) *DedupCore {
+	if window <= 0 {
+		window = time.Second
+	}
+	return &DedupCore{Core: core, d: &deduper{window: window, entries: map[dedupKey]*dedupEntry{}}}
+}
+
+// With implement zapcore.Core
+func (c *DedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &DedupCore{Core: c.Core.With(fields), d: c.d}
+}
+
+// Check implement zapcore.Core
+func (c *DedupCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(e.Level) {
+		return ce
+	}
+	// never hold back the entries which terminate the process
+	if e.Level > zapcore.ErrorLevel {
+		return c.Core.Check(e, ce)
+	}
+	return ce.AddCore(e, c)
+}
+
+// Write implement zapcore.Core
+func (c *DedupCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	d := c.d
+	key := dedupKey{level: e.Level, logger: e.LoggerName, message: e.Message}
+
+	d.mu.Lock()
+	prev, ok := d.entries[key]
+	if ok && e.Time.Sub(prev.first) < d.window {
+		prev.count++
+		// the fields are written after Write returns, the caller may reuse or change them
+		prev.entry, prev.fields, prev.core = e, freezeFields(fields), c.Core
+		d.mu.Unlock()
+		d.stats.suppressed.Add(1)
+		globalSampling.suppressed.Add(1)
+		return nil
+	}
+	d.entries[key] = &dedupEntry{first: e.Time, entry: e, core: c.Core}
+	startFlush := !d.flushing
+	d.flushing = true
+	d.mu.Unlock()
+
+	if startFlush {
+		go d.flushLoop()
+	}
+	if ok {
+		d.summary(prev)
+	}
+	writeChecked(c.Core, e, fields)
+	return nil
+}
+
+// Sync implement zapcore.Core, the pending summaries are written.
+func (c *DedupCore) Sync() error {
+	c.d.flush(time.Time{})
+	return c.Core.Sync()
+}
+
+// Stats return the stats of the dedup core.
+func (c *DedupCore) Stats() SamplingStats {
+	return c.d.stats.snapshot()
+}
+
+// flushLoop write the summaries of the ended windows, it exits when no entry is pending.
+func (d *deduper) flushLoop() {
+	ticker := time.NewTicker(d.window)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if d.flush(now) == 0 {
+			return
+		}
+	}
+}
+
+// flush write the summaries of the windows ended before now, or all if now is zero,
+// return the number of pending entries.
+func (d *deduper) flush(now time.Time) int {
+	var ended []*dedupEntry
+	d.mu.Lock()
+	for key, e := range d.entries {
+		if now.IsZero() || now.Sub(e.first) >= d.window {
+			delete(d.entries, key)
+			ended = append(ended, e)
+		}
+	}
+	pending := len(d.entries)
+	if pending == 0 && !now.IsZero() {
+		d.flushing = false
+	}
+	d.mu.Unlock()
+
+	for _, e := range ended {
+		d.summary(e)
+	}
+	return pending
+}
+
+// summary write the last repeated entry with the count
+func (d *deduper) summary(e *dedupEntry) {
+	if e.count == 0 {
+		return
+	}
+	d.stats.summaries.Add(1)
+	globalSampling.summaries.Add(1)
+	fields := append(e.fields[:len(e.fields):len(e.fields)], zap.Int64("repeated", e.count))
+	writeChecked(e.core, e.entry, fields)
+}
+
+// freezeFields copy the fields, the objects, arrays and stringers are evaluated now
+func freezeFields(fields []zapcore.Field) []zapcore.Field {
+	frozen := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.StringerType:
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			frozen[i] = zap.Any(f.Key, enc.Fields[f.Key])
+		default:
+			frozen[i] = f
+		}
+	}
+	return frozen
+}
+
+// writeChecked check the core so the sink levels apply
+func writeChecked(core zapcore.Core, e zapcore.Entry, fields []zapcore.Field) {
+	if ce := core.Check(e, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
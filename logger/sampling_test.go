@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSamplerCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	sc, err := NewSamplerCore(core, &SamplingConfig{
+		Tick:       time.Minute,
+		Initial:    2,
+		Thereafter: 3,
+		Levels:     map[string]SamplingRule{"error": {}},
+		Messages:   []MessageSamplingRule{{Message: "rpc failed", Level: "warn", SamplingRule: SamplingRule{Initial: 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := zap.New(sc)
+	for i := 0; i < 10; i++ {
+		l.Info("hot")
+		l.Error("always")
+		l.With(zap.Int("i", i)).Warn("rpc failed: unavailable")
+	}
+
+	counts := map[string]int{}
+	for _, e := range logs.All() {
+		counts[e.Message]++
+	}
+	// 1, 2, then 5 and 8
+	if counts["hot"] != 4 || counts["always"] != 10 || counts["rpc failed: unavailable"] != 1 {
+		t.Errorf("unexpected sampled counts %v", counts)
+	}
+	if s := sc.Stats(); s.Sampled != 15 || s.Dropped != 15 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestDedupCore(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	dc := NewDedupCore(core, 50*time.Millisecond)
+	l := zap.New(dc)
+	for i := 0; i < 5; i++ {
+		l.Error("downstream unavailable", zap.Int("i", i))
+	}
+	l.Debug("filtered by the sink level")
+	l.Info("other")
+
+	if got := logs.Len(); got != 2 {
+		t.Fatalf("expected the first entry and the other, got %d", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for logs.Len() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	entries := logs.FilterField(zap.Int64("repeated", 4)).All()
+	if len(entries) != 1 || entries[0].ContextMap()["i"] != int64(4) {
+		t.Fatalf("expected the summary of the last repeated entry, got %v", logs.All())
+	}
+	if s := dc.Stats(); s.Suppressed != 4 || s.Summaries != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	// the summary is flushed on sync
+	l.Warn("again")
+	l.Warn("again")
+	_ = l.Sync()
+	if logs.FilterMessage("again").Len() != 2 {
+		t.Errorf("expected the summary written on sync, got %v", logs.FilterMessage("again").All())
+	}
+}
+
+func TestDedupCoreFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := zap.New(NewDedupCore(core, time.Minute))
+
+	state := "first"
+	fields := []zap.Field{zap.String("user", "a"), zap.Object("req", zapcore.ObjectMarshalerFunc(
+		func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("state", state)
+			return nil
+		}))}
+	l.Warn("reused", fields...)
+	l.Warn("reused", fields...)
+	// the caller reuses the slice and changes the object after logging
+	fields[0] = zap.String("user", "b")
+	state = "changed"
+	_ = l.Sync()
+
+	entries := logs.FilterField(zap.Int64("repeated", 1)).All()
+	if len(entries) != 1 {
+		t.Fatalf("expected the summary, got %v", logs.All())
+	}
+	m := entries[0].ContextMap()
+	if m["user"] != "a" || m["req"].(map[string]interface{})["state"] != "first" {
+		t.Errorf("the summary is changed by the caller: %v", m)
+	}
+}
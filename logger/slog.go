@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlogHandler the slog.Handler backed by the core of zap logger, so the slog records share the sinks,
+// sampling and the runtime levels of the logger.
+type SlogHandler struct {
+	core zapcore.Core
+	name string
+	// group the open group without attrs yet, it is dropped if no attrs are added
+	group string
+}
+
+// NewSlogHandler new the slog handler by the logger, the default logger if it is nil.
+// The fields of the context, such as request_id and trace_id, are added to the records logged with context.
+func NewSlogHandler(l *zap.Logger) *SlogHandler {
+	if l == nil {
+		l = std()
+	}
+	return &SlogHandler{core: l.Core(), name: l.Name()}
+}
+
+// Slog return the slog logger backed by the default logger.
+func Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(std()))
+}
+
+// Enabled implement slog.Handler
+func (h *SlogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return h.core.Enabled(zapLevel(l))
+}
+
+// Handle implement slog.Handler
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := zapcore.Entry{
+		Level:      zapLevel(r.Level),
+		Time:       r.Time,
+		Message:    r.Message,
+		LoggerName: h.name,
+	}
+	ce := h.core.Check(e, nil)
+	if ce == nil {
+		return nil
+	}
+	if r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(f.PC, f.File, f.Line, true)
+	}
+
+	fields := make([]zap.Field, 0, r.NumAttrs()+4)
+	if ctx != nil {
+		fields = appendContextFields(fields, ctx)
+	}
+	if h.group != "" && r.NumAttrs() > 0 {
+		fields = append(fields, zap.Namespace(h.group))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs implement slog.Handler
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]zap.Field, 0, len(attrs)+1)
+	if h.group != "" {
+		fields = append(fields, zap.Namespace(h.group))
+	}
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	return &SlogHandler{core: h.core.With(fields), name: h.name}
+}
+
+// WithGroup implement slog.Handler
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	if c.group != "" {
+		// the empty parent group is kept since the child is nested in it
+		c.core = c.core.With([]zap.Field{zap.Namespace(c.group)})
+	}
+	c.group = name
+	return &c
+}
+
+// zapLevel map the slog level to zap level
+func zapLevel(l slog.Level) zapcore.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case l < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case l < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// appendAttr convert the attr to zap field, the empty attrs are ignored as slog does.
+func appendAttr(fields []zap.Field, a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			// inline the attrs of the group without key
+			for _, ga := range attrs {
+				fields = appendAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, groupObject(attrs)))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, v.Any()))
+	}
+}
+
+// groupObject the attrs of slog group
+type groupObject []slog.Attr
+
+// MarshalLogObject implement zapcore.ObjectMarshaler
+func (g groupObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, a := range g {
+		for _, f := range appendAttr(nil, a) {
+			f.AddTo(enc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/goriller/ginny/interceptor/tags"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSlogHandler(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := slog.New(NewSlogHandler(zap.New(core).Named("app")))
+
+	l.Debug("dropped")
+	ctx := tags.InjectIntoContext(context.Background(), tags.NewTags())
+	tags.Extract(ctx).Set("request_id", "req-1")
+	l.With("user", "u1").WithGroup("req").InfoContext(ctx, "hello",
+		"n", 1, "ok", true, "d", time.Second, "err", errors.New("boom"),
+		slog.Group("g", "a", "b"))
+	l.Log(context.Background(), slog.LevelWarn+1, "warn")
+	l.Error("error", slog.Group("empty"))
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("expect 3 entries, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Level != zapcore.InfoLevel || e.Message != "hello" || e.LoggerName != "app" || !e.Caller.Defined {
+		t.Errorf("unexpected entry %+v", e.Entry)
+	}
+	m := e.ContextMap()
+	if m["user"] != "u1" || m["request_id"] != "req-1" {
+		t.Errorf("unexpected fields %v", m)
+	}
+	req, ok := m["req"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expect the req group, got %v", m)
+	}
+	if req["n"] != int64(1) || req["ok"] != true || req["d"] != time.Second || req["err"] != "boom" {
+		t.Errorf("unexpected group fields %v", req)
+	}
+	if g, _ := req["g"].(map[string]interface{}); g["a"] != "b" {
+		t.Errorf("unexpected nested group %v", req["g"])
+	}
+	if entries[1].Level != zapcore.WarnLevel || entries[2].Level != zapcore.ErrorLevel {
+		t.Errorf("unexpected levels %v %v", entries[1].Level, entries[2].Level)
+	}
+	if _, ok := entries[2].ContextMap()["empty"]; ok {
+		t.Error("expect the empty group to be dropped")
+	}
+}
+
+func TestSlogHandlerNamedLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	h := NewSlogHandler(zap.New(&levelCore{Core: core, name: "slogtest", level: level}))
+	SetNamedLevel("slogtest", zapcore.ErrorLevel, 0)
+	defer ResetNamedLevel("slogtest")
+
+	l := slog.New(h)
+	l.Warn("dropped")
+	l.Error("kept")
+	if logs.Len() != 1 || !h.Enabled(context.Background(), slog.LevelError) || h.Enabled(context.Background(), slog.LevelWarn) {
+		t.Errorf("expect the named level to apply, got %d entries", logs.Len())
+	}
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// facilities the syslog facilities
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogCore write the entries to the syslog server in RFC 3164 format,
+// the severity is mapped from the level.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *syslogWriter
+}
+
+// newSyslogCore
+func newSyslogCore(s *SinkConfig, enc zapcore.Encoder, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	if s.Address == "" {
+		return nil, fmt.Errorf("the address of syslog sink is required")
+	}
+	facility := facilities["user"]
+	if s.Facility != "" {
+		f, ok := facilities[strings.ToLower(s.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility %q", s.Facility)
+		}
+		facility = f
+	}
+	network := s.Network
+	if network == "" {
+		network = "udp"
+	}
+	tag := s.Tag
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	hostname, _ := os.Hostname()
+	return &syslogCore{
+		LevelEnabler: enabler,
+		enc:          enc,
+		w: &syslogWriter{
+			network:  network,
+			address:  s.Address,
+			facility: facility,
+			tag:      tag,
+			hostname: hostname,
+			pid:      os.Getpid(),
+		},
+	}, nil
+}
+
+// With implement zapcore.Core
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w}
+}
+
+// Check implement zapcore.Core
+func (c *syslogCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+// Write implement zapcore.Core
+func (c *syslogCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(e, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	return c.w.write(severity(e.Level), e.Time, RedactBytes(buf.Bytes()))
+}
+
+// Sync implement zapcore.Core
+func (c *syslogCore) Sync() error {
+	return nil
+}
+
+// severity map the level to syslog severity
+func severity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// syslogWriter the connection to the syslog server, reconnected on failure.
+type syslogWriter struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+	pid      int
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// write
+func (w *syslogWriter) write(severity int, t time.Time, msg []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line := fmt.Sprintf("<%d>%s %s %s[%d]: %s", w.facility*8+severity, t.Format(time.Stamp),
+		w.hostname, w.tag, w.pid, strings.TrimRight(string(msg), "\n"))
+	// the stream networks need the trailer to frame the messages
+	if strings.HasPrefix(w.network, "tcp") || w.network == "unix" {
+		line += "\n"
+	}
+	// retry once with a new connection
+	var err error
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if w.conn, err = net.DialTimeout(w.network, w.address, time.Second); err != nil {
+				w.conn = nil
+				return err
+			}
+		}
+		if _, err = w.conn.Write([]byte(line)); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goriller/ginny/cache"
+	"github.com/goriller/ginny/interceptor"
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/goriller/ginny/server/mux/rewriter"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+)
+
+// cachedResponse the cached gateway response
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+	ETag        string `json:"etag"`
+	ExpireAt    int64  `json:"expire_at"`
+}
+
+// CacheMiddleWare serves the GET responses of the methods configured in the ResponseCache
+// from the cache, with ETag and Cache-Control headers, and answers a matching If-None-Match
+// with 304 Not Modified. "Cache-Control: no-cache" or the bypass header skips reading the
+// cache, "Cache-Control: no-store" skips the cache entirely.
+//
+// It is a gateway middleware set by runtime.WithMiddlewares, which runs once the route is matched.
+// The gRPC method of a route is learned from its first response, the cache is only read for
+// the routes of the cached methods.
+func CacheMiddleWare(rc *interceptor.ResponseCache) runtime.Middleware {
+	headers := cacheHeaders(rc)
+	// routes the gRPC method by the route pattern, which are as many as the routes
+	var routes sync.Map
+	return func(h runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				h(w, r, params)
+				return
+			}
+			control := strings.ToLower(r.Header.Get("Cache-Control"))
+			if strings.Contains(control, "no-store") {
+				h(w, r, params)
+				return
+			}
+			var route string
+			if p, ok := runtime.HTTPPattern(r.Context()); ok {
+				route = r.Method + " " + p.String()
+			}
+			learned, known := routes.Load(route)
+			if known {
+				if _, ok := rc.Rule(learned.(string)); !ok {
+					h(w, r, params)
+					return
+				}
+			}
+
+			values := make([]string, len(headers))
+			for i, k := range headers {
+				values[i] = r.Header.Get(k)
+			}
+			key := "http:" + interceptor.CacheKey(r.URL.Path, []byte(r.URL.Query().Encode()), values)
+
+			if known && r.Header.Get(rc.Bypass()) == "" && !strings.Contains(control, "no-cache") {
+				var cached cachedResponse
+				if err := rc.Cache.Get(r.Context(), key, &cached); err == nil {
+					writeCached(w, r, &cached, headers, "HIT")
+					return
+				}
+			}
+
+			// the method of a new route is known once the gateway handler starts, so whether
+			// the response is buffered for caching is decided on the first write
+			raw := w
+			rw, ok := w.(*rewriter.ResponseWriter)
+			if ok {
+				raw = rw.Writer
+			}
+			cw := &cacheWriter{raw: raw}
+			var next http.ResponseWriter = cw
+			var inner *rewriter.ResponseWriter
+			if ok {
+				// keep the rewriter so that errors still set the status of the response
+				cp := *rw
+				cp.Writer = cw
+				inner = &cp
+				next = inner
+			}
+			method := func() string {
+				m, _ := tags.Extract(r.Context()).Values()["action"].(string)
+				return m
+			}
+			succeeded := func() bool {
+				return inner == nil || (inner.Status.Code() == codes.OK && inner.HeaderStatus == http.StatusOK)
+			}
+			cw.cacheable = func() bool {
+				_, ok := rc.Rule(method())
+				return ok && succeeded()
+			}
+
+			h(next, r, params)
+			if inner != nil {
+				rw.Status, rw.HeaderStatus = inner.Status, inner.HeaderStatus
+			}
+			if m := method(); m != "" && route != "" && !known {
+				routes.Store(route, m)
+			}
+			if !cw.buffering {
+				return
+			}
+
+			rule, ok := rc.Rule(method())
+			if !ok || !succeeded() || (cw.status != 0 && cw.status != http.StatusOK) {
+				if cw.status != 0 {
+					raw.WriteHeader(cw.status)
+				}
+				_, _ = raw.Write(cw.body.Bytes())
+				return
+			}
+			body := cw.body.Bytes()
+			sum := sha256.Sum256(body)
+			cached := &cachedResponse{
+				ContentType: raw.Header().Get("Content-Type"),
+				Body:        body,
+				ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+				ExpireAt:    time.Now().Add(rule.TTL).UnixNano(),
+			}
+			_ = rc.Cache.Set(r.Context(), key, cached, rule.TTL, cache.WithTags(method()))
+			writeCached(w, r, cached, headers, "MISS")
+		}
+	}
+}
+
+// cacheHeaders the sorted metadata headers of all rules, part of the key of every response
+func cacheHeaders(rc *interceptor.ResponseCache) []string {
+	seen := map[string]bool{}
+	var headers []string
+	for _, rule := range rc.Methods {
+		for _, k := range rule.Metadata {
+			k = textproto.CanonicalMIMEHeaderKey(k)
+			if !seen[k] {
+				seen[k] = true
+				headers = append(headers, k)
+			}
+		}
+	}
+	sort.Strings(headers)
+	return headers
+}
+
+// writeCached write the cached response, or 304 if the client has the same version
+func writeCached(w http.ResponseWriter, r *http.Request, cached *cachedResponse, headers []string, state string) {
+	raw := w
+	rw, ok := w.(*rewriter.ResponseWriter)
+	if ok {
+		raw = rw.Writer
+	}
+	maxAge := time.Until(time.Unix(0, cached.ExpireAt)) / time.Second
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	header := raw.Header()
+	header.Set("ETag", cached.ETag)
+	header.Set("Cache-Control", "private, max-age="+strconv.Itoa(int(maxAge)))
+	header.Set(interceptor.CacheStatusHeader, state)
+	if len(headers) > 0 {
+		header.Set("Vary", strings.Join(headers, ", "))
+	}
+	if etagMatch(r.Header.Get("If-None-Match"), cached.ETag) {
+		if ok {
+			rw.HeaderStatus = http.StatusNotModified
+		}
+		header.Set(ResponseStatusHeader, strconv.Itoa(http.StatusNotModified))
+		raw.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if cached.ContentType != "" {
+		header.Set("Content-Type", cached.ContentType)
+	}
+	_, _ = raw.Write(cached.Body)
+}
+
+// etagMatch whether the If-None-Match header matches the etag, weak comparison
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheWriter buffers the response if it is cacheable, otherwise writes through
+type cacheWriter struct {
+	raw       http.ResponseWriter
+	cacheable func() bool
+	decided   bool
+	buffering bool
+	status    int
+	body      bytes.Buffer
+}
+
+func (w *cacheWriter) decide() {
+	if !w.decided {
+		w.decided = true
+		w.buffering = w.cacheable()
+	}
+}
+
+// Header implement http.ResponseWriter
+func (w *cacheWriter) Header() http.Header {
+	return w.raw.Header()
+}
+
+// Write implement http.ResponseWriter
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(b)
+	}
+	return w.raw.Write(b)
+}
+
+// WriteHeader implement http.ResponseWriter
+func (w *cacheWriter) WriteHeader(s int) {
+	w.decide()
+	if w.buffering {
+		w.status = s
+		return
+	}
+	w.raw.WriteHeader(s)
+}
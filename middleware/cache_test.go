@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goriller/ginny/cache"
+	"github.com/goriller/ginny/interceptor"
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/goriller/ginny/server/mux/rewriter"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCacheMiddleWare(t *testing.T) {
+	mc := cache.NewMemoryCache(cache.MemoryCacheConfig{})
+	defer mc.Close()
+	c := &countingCache{Cache: mc}
+	rc := &interceptor.ResponseCache{
+		Cache: c,
+		Methods: map[string]interceptor.CacheRule{
+			"/pkg.Service/Get": {TTL: time.Minute, Metadata: []string{"x-tenant"}},
+		},
+	}
+
+	calls := 0
+	handler := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		calls++
+		tags.Extract(r.Context()).Set("action", "/pkg.Service"+r.URL.Path)
+		if r.URL.Path == "/Missing" {
+			rewriter.WriteHTTPErrorResponse(w, r, status.Error(codes.NotFound, "not found"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"` + r.URL.Query().Get("id") + `"}`))
+	}
+	gateway := runtime.NewServeMux(runtime.WithMiddlewares(CacheMiddleWare(rc)))
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/Get"}, {http.MethodGet, "/List"}, {http.MethodGet, "/Missing"}, {http.MethodPost, "/Get"},
+	} {
+		if err := gateway.HandlePath(route.method, route.path, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	marshaler := &runtime.JSONPb{}
+	h := RecoverMiddleWare(nil, marshaler, marshaler, true)(gateway)
+	serve := func(method, target string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	first := serve(http.MethodGet, "/Get?id=1", map[string]string{"X-Tenant": "a"})
+	if first.Header().Get(interceptor.CacheStatusHeader) != "MISS" {
+		t.Fatalf("first request: %v", first.Header())
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" || first.Header().Get("Cache-Control") != "private, max-age=59" &&
+		first.Header().Get("Cache-Control") != "private, max-age=60" {
+		t.Fatalf("cache headers: %v", first.Header())
+	}
+
+	second := serve(http.MethodGet, "/Get?id=1", map[string]string{"X-Tenant": "a"})
+	if calls != 1 || second.Header().Get(interceptor.CacheStatusHeader) != "HIT" {
+		t.Fatalf("expect a hit, calls = %d, headers %v", calls, second.Header())
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("ETag") != etag {
+		t.Errorf("cached response %q, want %q", second.Body.String(), first.Body.String())
+	}
+	if second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("content type %q", second.Header().Get("Content-Type"))
+	}
+
+	notModified := serve(http.MethodGet, "/Get?id=1", map[string]string{"X-Tenant": "a", "If-None-Match": etag})
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Errorf("If-None-Match: code %d, body %q", notModified.Code, notModified.Body.String())
+	}
+
+	// another tenant, query or a bypass header reaches the handler
+	serve(http.MethodGet, "/Get?id=1", map[string]string{"X-Tenant": "b"})
+	serve(http.MethodGet, "/Get?id=2", map[string]string{"X-Tenant": "a"})
+	serve(http.MethodGet, "/Get?id=1", map[string]string{"X-Tenant": "a", interceptor.CacheBypassHeader: "1"})
+	serve(http.MethodGet, "/Get?id=1", map[string]string{"X-Tenant": "a", "Cache-Control": "no-cache"})
+	if calls != 5 {
+		t.Errorf("calls = %d, want 5", calls)
+	}
+
+	// errors, methods without a rule and writes are not cached
+	for i := 0; i < 2; i++ {
+		if w := serve(http.MethodGet, "/Missing", nil); w.Header().Get(interceptor.CacheStatusHeader) != "" {
+			t.Errorf("error response cached: %v", w.Header())
+		}
+		serve(http.MethodGet, "/List", nil)
+		serve(http.MethodPost, "/Get?id=1", map[string]string{"X-Tenant": "a"})
+	}
+	if calls != 11 {
+		t.Errorf("calls = %d, want 11", calls)
+	}
+
+	// the routes of the methods without a rule never read the cache
+	gets := c.gets
+	serve(http.MethodGet, "/List", nil)
+	if c.gets != gets {
+		t.Errorf("the cache is read for a route without a rule")
+	}
+
+	if err := c.InvalidateTags(t.Context(), "/pkg.Service/Get"); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(http.MethodGet, "/Get?id=1", map[string]string{"X-Tenant": "a"}); w.Header().Get(interceptor.CacheStatusHeader) != "MISS" {
+		t.Errorf("invalidated response served: %v", w.Header())
+	}
+}
+
+// countingCache count the reads of the cache
+type countingCache struct {
+	cache.Cache
+	gets int
+}
+
+func (c *countingCache) Get(ctx context.Context, key string, dest interface{}) error {
+	c.gets++
+	return c.Cache.Get(ctx, key, dest)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/goriller/ginny/logger"
+	"go.uber.org/zap"
+)
+
+// LoggerMiddleWare store the request scoped logger with the tags and trace ids in the context,
+// the handlers get it by logger.FromContext. It should be chained after the tracer.
+func LoggerMiddleWare(l *zap.Logger) MuxMiddleware {
+	if l == nil {
+		l = logger.Default()
+	}
+	return func(h http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			ctx = logger.SetContextLogger(ctx, l.With(logger.ContextFields(ctx)...))
+			h.ServeHTTP(w, r.WithContext(ctx))
+		}
+	}
+}
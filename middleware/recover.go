@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/goriller/ginny/interceptor/logging"
 	"github.com/goriller/ginny/interceptor/tags"
 	"github.com/goriller/ginny/server/mux/rewriter"
 	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
@@ -23,9 +26,53 @@ func DisableRecover() {
 	recoverPanic = false
 }
 
-// RecoverMiddleWare revover add logger
+// ValueRedactor mask the sensitive values of the access log by the key.
+type ValueRedactor interface {
+	Value(key, value string) (string, bool)
+}
+
+// LogOption the option of the access log
+type LogOption func(*logOptions)
+
+type logOptions struct {
+	redactors []ValueRedactor
+	decider   logging.Decider
+	levelFunc grpc_logging.CodeToLevel
+}
+
+// WithLogRedactor mask the values of the access log by the redactor.
+func WithLogRedactor(r ValueRedactor) LogOption {
+	return func(o *logOptions) {
+		if r != nil {
+			o.redactors = append(o.redactors, r)
+		}
+	}
+}
+
+// WithLogDecider decide whether the request is logged, the method is the gRPC full method of the
+// gateway route, or the url path if the route is not a gRPC method. The request is logged only
+// if the events of the decision is logging.FinishCall.
+func WithLogDecider(d logging.Decider) LogOption {
+	return func(o *logOptions) {
+		o.decider = d
+	}
+}
+
+// WithLogLevels map the gRPC code of the response to the log level,
+// by default the level is mapped from the http status.
+func WithLogLevels(f grpc_logging.CodeToLevel) LogOption {
+	return func(o *logOptions) {
+		o.levelFunc = f
+	}
+}
+
+// RecoverMiddleWare revover add logger, the access log is written by the options.
 func RecoverMiddleWare(logger grpc_logging.Logger, bodyMarshaler,
-	errorMarshaler runtime.Marshaler, withoutHTTPStatus bool) MuxMiddleware {
+	errorMarshaler runtime.Marshaler, withoutHTTPStatus bool, opts ...LogOption) MuxMiddleware {
+	lo := &logOptions{}
+	for _, fn := range opts {
+		fn(lo)
+	}
 	return func(h http.Handler) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -47,7 +94,7 @@ func RecoverMiddleWare(logger grpc_logging.Logger, bodyMarshaler,
 						tags.Extract(r.Context()).Set("stacktrace", stack)
 						err := status.Errorf(codes.Internal, "%s", rec)
 						rewriter.WriteHTTPErrorResponse(wt, req, err)
-						withLogger(ctx, start, logger, req, wt)
+						withLogger(ctx, start, logger, req, wt, lo)
 					}
 				}
 			}(writer, r)
@@ -61,44 +108,104 @@ func RecoverMiddleWare(logger grpc_logging.Logger, bodyMarshaler,
 			h.ServeHTTP(writer, r)
 
 			// logger
-			withLogger(ctx, start, logger, r, writer)
+			withLogger(ctx, start, logger, r, writer, lo)
 		}
 	}
 }
 
 // withLogger
-func withLogger(ctx context.Context, start time.Time, logger grpc_logging.Logger, r *http.Request, w http.ResponseWriter) {
+func withLogger(ctx context.Context, start time.Time, logger grpc_logging.Logger, r *http.Request, w http.ResponseWriter,
+	opts *logOptions) {
 	if logger == nil {
 		return
 	}
-	fields := getLoggingFields(start, r, w)
+	st := responseStatus(w)
+	if opts.decider != nil {
+		method, _ := tags.Extract(r.Context()).Values()["action"].(string)
+		if method == "" {
+			method = r.URL.Path
+		}
+		if opts.decider(method, st.Err()).Events != grpc_logging.FinishCall {
+			return
+		}
+	}
+	fields := getLoggingFields(start, r, w, opts.redactors)
 	status := w.Header().Get(ResponseStatusHeader)
 	level := getLevel(status, w)
+	if opts.levelFunc != nil && st != nil {
+		level = opts.levelFunc(st.Code())
+	}
 	if status != "" {
 		fields = append(fields, "status", status)
 	}
 	logger.Log(ctx, level, "finished call", fields...)
 }
 
+// responseStatus the gRPC status of the response, nil if unknown
+func responseStatus(w http.ResponseWriter) *status.Status {
+	if rw, ok := w.(*rewriter.ResponseWriter); ok {
+		return rw.Status
+	}
+	return nil
+}
+
 // getLoggingFields returns all fields from tags.
 func getLoggingFields(start time.Time,
-	r *http.Request, w http.ResponseWriter) grpc_logging.Fields {
+	r *http.Request, w http.ResponseWriter, redactors []ValueRedactor) grpc_logging.Fields {
 	var fields grpc_logging.Fields
 	preTags := tags.Extract(r.Context()).Values()
 	for k, v := range preTags {
+		if s, ok := v.(string); ok {
+			v = redactValue(redactors, k, s)
+		}
 		fields = append(fields, k, v)
 	}
 	fields = append(fields, "path", r.URL.Path)
 	fields = append(fields, "host", r.Host)
 	fields = append(fields, "method", r.Method)
 	fields = append(fields, "protocol", r.Proto)
-	fields = append(fields, "referer", r.Header.Get("referer"))
+	fields = append(fields, "referer", redactURL(redactors, r.Header.Get("referer")))
 	fields = append(fields, "device_id", r.Header.Get(DeviceIDHeader))
 	used := float32(time.Since(start)) / float32(time.Millisecond)
 	fields = append(fields, "time_ms", fmt.Sprintf("%3f", used))
 	return fields
 }
 
+// redactValue
+func redactValue(redactors []ValueRedactor, key, value string) string {
+	for _, rd := range redactors {
+		if v, ok := rd.Value(key, value); ok {
+			return v
+		}
+	}
+	return value
+}
+
+// redactURL mask the query parameters of the url
+func redactURL(redactors []ValueRedactor, raw string) string {
+	if len(redactors) == 0 || !strings.Contains(raw, "?") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	changed := false
+	for k, vs := range q {
+		for i, v := range vs {
+			if rv := redactValue(redactors, k, v); rv != v {
+				vs[i], changed = rv, true
+			}
+		}
+	}
+	if !changed {
+		return raw
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
 // getLevel
 func getLevel(status string, w http.ResponseWriter) (logLevel grpc_logging.Level) {
 	statusCode, err := strconv.Atoi(status)
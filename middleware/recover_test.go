@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goriller/ginny/interceptor/logging"
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/goriller/ginny/server/mux/rewriter"
+	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type logEntry struct {
+	level  grpc_logging.Level
+	msg    string
+	fields map[string]interface{}
+}
+
+type captureLogger struct {
+	entries []logEntry
+}
+
+func (l *captureLogger) Log(_ context.Context, level grpc_logging.Level, msg string, fields ...any) {
+	m := map[string]interface{}{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		m[fields[i].(string)] = fields[i+1]
+	}
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: m})
+}
+
+func TestRecoverMiddleWareLogOptions(t *testing.T) {
+	marshaler := &runtime.JSONPb{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tags.Extract(r.Context()).Set("action", "/pkg.Service"+r.URL.Path)
+		if r.URL.Path == "/Missing" {
+			rewriter.WriteHTTPErrorResponse(w, r, status.Error(codes.NotFound, "not found"))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+	var methods []string
+	decider := func(method string, err error) logging.PayloadDecision {
+		methods = append(methods, method)
+		if method == "/pkg.Service/Quiet" {
+			return logging.PayloadDecision{}
+		}
+		return logging.PayloadDecision{Events: grpc_logging.FinishCall}
+	}
+	levels := func(code codes.Code) grpc_logging.Level {
+		if code == codes.NotFound {
+			return grpc_logging.LevelError
+		}
+		return grpc_logging.LevelDebug
+	}
+
+	logger := &captureLogger{}
+	h := RecoverMiddleWare(logger, marshaler, marshaler, true,
+		WithLogDecider(decider), WithLogLevels(levels))(handler)
+	for _, path := range []string{"/Quiet", "/Hello", "/Missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := []string{"/pkg.Service/Quiet", "/pkg.Service/Hello", "/pkg.Service/Missing"}
+	if len(methods) != len(want) {
+		t.Fatalf("decider called with %v", methods)
+	}
+	for i := range want {
+		if methods[i] != want[i] {
+			t.Errorf("decider method %d = %q, want %q", i, methods[i], want[i])
+		}
+	}
+	if len(logger.entries) != 2 {
+		t.Fatalf("expect 2 access logs, got %d", len(logger.entries))
+	}
+	if e := logger.entries[0]; e.level != grpc_logging.LevelDebug || e.fields["action"] != "/pkg.Service/Hello" {
+		t.Errorf("unexpected log %+v", e)
+	}
+	if e := logger.entries[1]; e.level != grpc_logging.LevelError || e.fields["status"] != "404" {
+		t.Errorf("unexpected log %+v", e)
+	}
+}
+
+func TestRecoverMiddleWareDefaultLevel(t *testing.T) {
+	marshaler := &runtime.JSONPb{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rewriter.WriteHTTPErrorResponse(w, r, status.Error(codes.Internal, "boom"))
+	})
+	logger := &captureLogger{}
+	RecoverMiddleWare(logger, marshaler, marshaler, true)(handler).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	if len(logger.entries) != 1 || logger.entries[0].level != grpc_logging.LevelError {
+		t.Fatalf("unexpected logs %+v", logger.entries)
+	}
+}
@@ -0,0 +1,221 @@
+// Package admin provide the admin handler served on the metrics address, with prometheus metrics,
+// pprof, channelz, build info, log level and caches, it should never be exposed on the public gateway port.
+// Without an AuthFunc, only /metrics is served to the remote clients, the other endpoints are loopback only.
+package admin
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/goriller/ginny/cache"
+	"github.com/goriller/ginny/logger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthFunc the admin auth func, the request is rejected with 401 if it returns error,
+// or 403 if it is a gRPC PermissionDenied status error.
+type AuthFunc func(r *http.Request) error
+
+// BuildInfo the build info of the application
+type BuildInfo struct {
+	Name      string            `json:"name"`
+	Version   string            `json:"version"`
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path,omitempty"`
+	Commit    string            `json:"commit,omitempty"`
+	Time      string            `json:"time,omitempty"`
+	Modified  bool              `json:"modified,omitempty"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+// options the options of admin handler
+type options struct {
+	authFunc  AuthFunc
+	name      string
+	version   string
+	handlers  map[string]http.Handler
+	noPprof   bool
+	noChannel bool
+}
+
+// Option the option of admin handler
+type Option func(*options)
+
+// WithAuthFunc guard all the admin endpoints by the auth func, and serve them to the remote clients.
+func WithAuthFunc(f AuthFunc) Option {
+	return func(o *options) {
+		o.authFunc = f
+	}
+}
+
+// WithBuildInfo set the application name and version of /buildinfo.
+func WithBuildInfo(name, version string) Option {
+	return func(o *options) {
+		o.name = name
+		o.version = version
+	}
+}
+
+// WithHandler add the handler to the admin endpoints, such as /config.
+func WithHandler(pattern string, h http.Handler) Option {
+	return func(o *options) {
+		if pattern != "" && h != nil {
+			o.handlers[pattern] = h
+		}
+	}
+}
+
+// WithoutPprof disable the /debug/pprof/ endpoints.
+func WithoutPprof() Option {
+	return func(o *options) {
+		o.noPprof = true
+	}
+}
+
+// WithoutChannelz disable the /channelz/ endpoints.
+func WithoutChannelz() Option {
+	return func(o *options) {
+		o.noChannel = true
+	}
+}
+
+// NewHandler new the admin handler, the endpoints other than /metrics only accept the requests from
+// loopback unless WithAuthFunc is set:
+//
+//	/metrics             prometheus metrics
+//	/debug/pprof/        pprof
+//	/channelz/           gRPC channelz, see ChannelzHandler
+//	/buildinfo           application name, version and vcs info
+//	/loglevel            GET and PUT {"level":"debug","logger":"cache","ttl":"10m"} the log levels
+//	/cache               GET the stats and keys of the registered caches, DELETE the keys by prefix, key or tag
+func NewHandler(opts ...Option) http.Handler {
+	o := &options{handlers: map[string]http.Handler{}}
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/buildinfo", buildInfoHandler(o.name, o.version))
+	mux.Handle("/loglevel", logger.LevelHandler())
+	mux.Handle("/cache", cache.Handler())
+	if !o.noPprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if !o.noChannel {
+		mux.Handle("/channelz/", http.StripPrefix("/channelz", ChannelzHandler()))
+	}
+	for pattern, h := range o.handlers {
+		mux.Handle(pattern, h)
+	}
+
+	var h http.Handler = mux
+	if o.authFunc == nil {
+		h = loopbackHandler(mux)
+	}
+	root := http.NewServeMux()
+	root.Handle("/metrics", promhttp.Handler())
+	root.Handle("/", h)
+	if o.authFunc == nil {
+		return root
+	}
+	return authHandler(o.authFunc, root)
+}
+
+// loopbackHandler reject the requests which are not from loopback with 403
+func loopbackHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authHandler
+func authHandler(authFunc AuthFunc, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := authFunc(r); err != nil {
+			code := http.StatusUnauthorized
+			if s, ok := status.FromError(err); ok && s.Code() == codes.PermissionDenied {
+				code = http.StatusForbidden
+			}
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ReadBuildInfo read the build info of the binary.
+func ReadBuildInfo(name, version string) *BuildInfo {
+	info := &BuildInfo{
+		Name:      name,
+		Version:   version,
+		GoVersion: runtime.Version(),
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Path = bi.Main.Path
+	info.Settings = map[string]string{}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.Time = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		default:
+			if strings.HasPrefix(s.Key, "-") || strings.HasPrefix(s.Key, "GO") || s.Key == "CGO_ENABLED" {
+				info.Settings[s.Key] = s.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = bi.Main.Version
+	}
+	return info
+}
+
+// buildInfoHandler
+func buildInfoHandler(name, version string) http.Handler {
+	info := ReadBuildInfo(name, version)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, info)
+	})
+}
+
+// writeJSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// JSONHandler serve the value returned by fn as json, such as the redacted config.
+func JSONHandler(fn func() interface{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, fn())
+	})
+}
@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthFunc(t *testing.T) {
+	h := NewHandler(WithAuthFunc(func(r *http.Request) error {
+		switch r.Header.Get("Authorization") {
+		case "Bearer admin":
+			return nil
+		case "Bearer user":
+			return status.Error(codes.PermissionDenied, "denied")
+		default:
+			return errors.New("unauthenticated")
+		}
+	}))
+	for token, code := range map[string]int{"": http.StatusUnauthorized, "user": http.StatusForbidden, "admin": http.StatusOK} {
+		r := httptest.NewRequest(http.MethodGet, "/buildinfo", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("token %q: expected %d, got %d", token, code, w.Code)
+		}
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	h := NewHandler(WithBuildInfo("demo", "v1.0.0"), WithHandler("/config", JSONHandler(func() interface{} {
+		return map[string]string{"password": "******"}
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, localRequest(http.MethodGet, "/buildinfo", nil))
+	var info BuildInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "demo" || info.Version != "v1.0.0" || info.GoVersion == "" {
+		t.Errorf("unexpected build info %+v", info)
+	}
+
+	for _, path := range []string{"/loglevel", "/cache", "/config", "/metrics", "/debug/pprof/", "/channelz/servers"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, localRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, localRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"warn"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "warn") {
+		t.Errorf("put level: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	h := NewHandler()
+	for path, code := range map[string]int{"/metrics": http.StatusOK, "/buildinfo": http.StatusForbidden,
+		"/loglevel": http.StatusForbidden, "/cache": http.StatusForbidden, "/debug/pprof/": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Errorf("remote %s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+	for _, addr := range []string{"127.0.0.1:1234", "[::1]:1234"} {
+		r := httptest.NewRequest(http.MethodGet, "/buildinfo", nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", addr, w.Code)
+		}
+	}
+}
+
+// localRequest the request from loopback
+func localRequest(method, target string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, target, body)
+	r.RemoteAddr = "127.0.0.1:1234"
+	return r
+}
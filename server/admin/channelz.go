@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc"
+	channelzgrpc "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/channelz/service"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	channelzOnce   sync.Once
+	channelzServer channelzgrpc.ChannelzServer
+)
+
+// registrar capture the channelz service implementation
+type registrar struct{}
+
+// RegisterService implement grpc.ServiceRegistrar
+func (registrar) RegisterService(_ *grpc.ServiceDesc, impl interface{}) {
+	channelzServer, _ = impl.(channelzgrpc.ChannelzServer)
+}
+
+// ChannelzHandler serve the gRPC channelz data as json:
+//
+//	/channels?start_id=0          top channels
+//	/channel?id=1                 channel
+//	/subchannel?id=1              subchannel
+//	/servers?start_id=0           servers
+//	/server?id=1                  server
+//	/server_sockets?id=1          sockets of server
+//	/socket?id=1                  socket
+func ChannelzHandler() http.Handler {
+	channelzOnce.Do(func() {
+		service.RegisterChannelzServiceToServer(registrar{})
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/channels", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, r, func(id int64) (proto.Message, error) {
+			return channelzServer.GetTopChannels(r.Context(), &channelzgrpc.GetTopChannelsRequest{StartChannelId: id})
+		}, "start_id")
+	})
+	mux.HandleFunc("/channel", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, r, func(id int64) (proto.Message, error) {
+			return channelzServer.GetChannel(r.Context(), &channelzgrpc.GetChannelRequest{ChannelId: id})
+		}, "id")
+	})
+	mux.HandleFunc("/subchannel", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, r, func(id int64) (proto.Message, error) {
+			return channelzServer.GetSubchannel(r.Context(), &channelzgrpc.GetSubchannelRequest{SubchannelId: id})
+		}, "id")
+	})
+	mux.HandleFunc("/servers", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, r, func(id int64) (proto.Message, error) {
+			return channelzServer.GetServers(r.Context(), &channelzgrpc.GetServersRequest{StartServerId: id})
+		}, "start_id")
+	})
+	mux.HandleFunc("/server", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, r, func(id int64) (proto.Message, error) {
+			return channelzServer.GetServer(r.Context(), &channelzgrpc.GetServerRequest{ServerId: id})
+		}, "id")
+	})
+	mux.HandleFunc("/server_sockets", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, r, func(id int64) (proto.Message, error) {
+			return channelzServer.GetServerSockets(r.Context(), &channelzgrpc.GetServerSocketsRequest{ServerId: id})
+		}, "id")
+	})
+	mux.HandleFunc("/socket", func(w http.ResponseWriter, r *http.Request) {
+		writeProto(w, r, func(id int64) (proto.Message, error) {
+			return channelzServer.GetSocket(r.Context(), &channelzgrpc.GetSocketRequest{SocketId: id})
+		}, "id")
+	})
+	return mux
+}
+
+// writeProto
+func writeProto(w http.ResponseWriter, r *http.Request, fn func(id int64) (proto.Message, error), key string) {
+	var id int64
+	if v := r.URL.Query().Get(key); v != "" {
+		var err error
+		if id, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "invalid "+key, http.StatusBadRequest)
+			return
+		}
+	}
+	if channelzServer == nil {
+		http.Error(w, "channelz is unavailable", http.StatusNotImplemented)
+		return
+	}
+	msg, err := fn(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	data, err := protojson.MarshalOptions{Multiline: true, UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
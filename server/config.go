@@ -0,0 +1,236 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/goriller/ginny/interceptor/limit"
+	"github.com/goriller/ginny/interceptor/logging"
+	"github.com/goriller/ginny/server/mux"
+	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Config the server config, bind from the `server` section of config file.
+// All the fields are optional, the zero value keeps the default of the server.
+//
+//	server:
+//	  tags: ["v1"]
+//	  maxSendMsgSize: 4194304
+//	  maxRecvMsgSize: 4194304
+//	  initialWindowSize: 1048576
+//	  initialConnWindowSize: 1048576
+//	  keepAlive:
+//	    disabled: false
+//	    maxConnectionIdle: 5m
+//	    maxConnectionAge: 1m
+//	    maxConnectionAgeGrace: 10s
+//	    time: 10s
+//	    timeout: 3s
+//	    minTime: 5s
+//	    permitWithoutStream: true
+//	  limiter:
+//	    disabled: false
+//	    default:
+//	      headers: ["x-device-id"]
+//	      quota: 100
+//	      duration: 1s
+//	    limit:
+//	      - prefix: /v1/user/
+//	        headers: ["x-user-id"]
+//	        quota: 10
+//	        duration: 1s
+//	    block:
+//	      - key: x-device-id
+//	        value: blocked
+//	  logging:
+//	    events: finish
+//	    request: false
+//	    response: false
+//	    clearBytes: true
+//	    stream: true
+//	    streamSample: 10
+//	    rules:
+//	      - prefix: /pkg.Service/Login
+//	        events: finish
+//	        request: false
+//	    redact:
+//	      strategy: mask
+//	      maxSize: 4096
+//	      fields:
+//	        - path: user.phone
+//	          strategy: last4
+//	  gateway:
+//	    httpStatus: false
+type Config struct {
+	Tags                  []string
+	MaxSendMsgSize        int
+	MaxRecvMsgSize        int
+	InitialWindowSize     int32
+	InitialConnWindowSize int32
+	KeepAlive             *KeepAliveConfig
+	Limiter               *limit.RouterLimit
+	Logging               *LoggingConfig
+	Gateway               *GatewayConfig
+}
+
+// KeepAliveConfig the keepalive config of gRPC server.
+type KeepAliveConfig struct {
+	// Disabled without keepalive options, gRPC will use its own defaults.
+	Disabled              bool
+	MaxConnectionIdle     time.Duration
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+	Time                  time.Duration
+	Timeout               time.Duration
+	MinTime               time.Duration
+	PermitWithoutStream   *bool
+}
+
+// LoggingConfig the payload logging config, the rules are matched by method prefix in order.
+type LoggingConfig struct {
+	LoggingRule `mapstructure:",squash"`
+	Rules       []LoggingRule
+	Redact      *RedactConfig
+}
+
+// LoggingRule the logging decision for methods with the prefix.
+type LoggingRule struct {
+	Prefix string
+	// Events one of start or finish, default finish.
+	Events     string
+	Request    bool
+	Response   bool
+	ClearBytes bool
+	// Stream log the streaming RPCs per message, see logging.PayloadDecision.
+	Stream       bool
+	StreamSample int
+}
+
+// RedactConfig the redaction of the payload logs and the gateway access logs.
+type RedactConfig struct {
+	// Strategy of the `(ginny.sensitive)` fields: mask, drop, hash or last4, unknown is treated as mask.
+	Strategy string
+	// MaxSize the max size of the payload, 2048 by default, no limit if < 0.
+	MaxSize int
+	Fields  []RedactField
+}
+
+// RedactField the field path to redact, see logging.WithPaths.
+type RedactField struct {
+	Path     string
+	Strategy string
+}
+
+// Redactor new the redactor by the config.
+func (c *RedactConfig) Redactor() *logging.Redactor {
+	s, _ := logging.ParseStrategy(c.Strategy)
+	opts := []logging.RedactOption{logging.WithStrategy(s)}
+	if c.MaxSize != 0 {
+		opts = append(opts, logging.WithMaxSize(c.MaxSize))
+	}
+	for _, f := range c.Fields {
+		fs, _ := logging.ParseStrategy(f.Strategy)
+		opts = append(opts, logging.WithPaths(fs, f.Path))
+	}
+	return logging.NewRedactor(opts...)
+}
+
+// GatewayConfig the gRPC-Gateway config.
+type GatewayConfig struct {
+	// HttpStatus response with the http status mapped from gRPC code, instead of 200.
+	HttpStatus bool
+}
+
+// Options convert the config to server options.
+func (c *Config) Options() []Option {
+	if c == nil {
+		return nil
+	}
+	opts := []Option{
+		WithTags(c.Tags),
+		WithMaxMsgSize(c.MaxSendMsgSize, c.MaxRecvMsgSize),
+		WithWindowSize(c.InitialWindowSize, c.InitialConnWindowSize),
+	}
+	if c.KeepAlive != nil {
+		if c.KeepAlive.Disabled {
+			opts = append(opts, WithoutKeepAlive())
+		} else {
+			params, policy := c.KeepAlive.params()
+			opts = append(opts, WithKeepAlive(params, policy))
+		}
+	}
+	if c.Limiter != nil {
+		opts = append(opts, WithLimiter(&limit.Limiter{Config: c.Limiter}))
+	}
+	if c.Logging != nil {
+		opts = append(opts, WithLoggingDecider(c.Logging.Decider()))
+		if c.Logging.Redact != nil {
+			opts = append(opts, WithRedactor(c.Logging.Redact.Redactor()))
+		}
+	}
+	if c.Gateway != nil && c.Gateway.HttpStatus {
+		opts = append(opts, WithHttpServerOption(mux.WithHTTPStatus()))
+	}
+	return opts
+}
+
+// params the keepalive params base on the default options.
+func (c *KeepAliveConfig) params() (keepalive.ServerParameters, keepalive.EnforcementPolicy) {
+	params := defaultOptions.keepAliveParams
+	policy := defaultOptions.keepAlivePolicy
+	if c.MaxConnectionIdle > 0 {
+		params.MaxConnectionIdle = c.MaxConnectionIdle
+	}
+	if c.MaxConnectionAge > 0 {
+		params.MaxConnectionAge = c.MaxConnectionAge
+	}
+	if c.MaxConnectionAgeGrace > 0 {
+		params.MaxConnectionAgeGrace = c.MaxConnectionAgeGrace
+	}
+	if c.Time > 0 {
+		params.Time = c.Time
+	}
+	if c.Timeout > 0 {
+		params.Timeout = c.Timeout
+	}
+	if c.MinTime > 0 {
+		policy.MinTime = c.MinTime
+	}
+	if c.PermitWithoutStream != nil {
+		policy.PermitWithoutStream = *c.PermitWithoutStream
+	}
+	return params, policy
+}
+
+// Decider the logging decider from config.
+func (c *LoggingConfig) Decider() logging.Decider {
+	return func(fullMethod string, _ error) logging.PayloadDecision {
+		for _, r := range c.Rules {
+			if strings.HasPrefix(fullMethod, r.Prefix) {
+				return r.decision()
+			}
+		}
+		return c.decision()
+	}
+}
+
+// decision
+func (r LoggingRule) decision() logging.PayloadDecision {
+	return logging.PayloadDecision{
+		Events:       loggableEvents(r.Events),
+		Request:      r.Request,
+		Response:     r.Response,
+		ClearBytes:   r.ClearBytes,
+		Stream:       r.Stream,
+		StreamSample: r.StreamSample,
+	}
+}
+
+// loggableEvents
+func loggableEvents(events string) grpc_logging.LoggableEvent {
+	if strings.EqualFold(events, "start") {
+		return grpc_logging.StartCall
+	}
+	return grpc_logging.FinishCall
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/spf13/viper"
+)
+
+const testConfig = `
+server:
+  tags: ["v1", "v2"]
+  maxSendMsgSize: 1024
+  maxRecvMsgSize: 2048
+  initialWindowSize: 4096
+  keepAlive:
+    maxConnectionAge: 2m
+    minTime: 5s
+    permitWithoutStream: false
+  limiter:
+    default:
+      headers: ["x-device-id"]
+      quota: 100
+      duration: 1s
+  logging:
+    request: true
+    rules:
+      - prefix: /pkg.Service/Login
+        events: start
+    redact:
+      fields:
+        - path: card
+          strategy: last4
+`
+
+func TestConfigOptions(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewBufferString(testConfig)); err != nil {
+		t.Fatal(err)
+	}
+	c := new(Config)
+	if err := v.UnmarshalKey("server", c); err != nil {
+		t.Fatal(err)
+	}
+
+	o := evaluateOptions(c.Options())
+	if o.maxSendMsgSize != 1024 || o.maxRecvMsgSize != 2048 {
+		t.Errorf("unexpected msg size %d/%d", o.maxSendMsgSize, o.maxRecvMsgSize)
+	}
+	if o.initialWindowSize != 4096 || o.initialConnWindowSize != InitialConnWindowSize {
+		t.Errorf("unexpected window size %d/%d", o.initialWindowSize, o.initialConnWindowSize)
+	}
+	if o.keepAliveParams.MaxConnectionAge != 2*time.Minute ||
+		o.keepAliveParams.Time != defaultOptions.keepAliveParams.Time {
+		t.Errorf("unexpected keepalive params %+v", o.keepAliveParams)
+	}
+	if o.keepAlivePolicy.MinTime != 5*time.Second || o.keepAlivePolicy.PermitWithoutStream {
+		t.Errorf("unexpected keepalive policy %+v", o.keepAlivePolicy)
+	}
+	if len(o.tags) < 2 || o.tags[0] != "v1" || o.tags[1] != "v2" {
+		t.Errorf("unexpected tags %v", o.tags)
+	}
+	if o.limiter == nil || o.limiter.Config.Default.Quota != 100 ||
+		o.limiter.Config.Default.Duration != time.Second {
+		t.Errorf("unexpected limiter %+v", o.limiter)
+	}
+
+	d := o.loggingDecider("/pkg.Service/Login", nil)
+	if d.Events != grpc_logging.StartCall || d.Request {
+		t.Errorf("unexpected rule decision %+v", d)
+	}
+	d = o.loggingDecider("/pkg.Service/Get", nil)
+	if d.Events != grpc_logging.FinishCall || !d.Request {
+		t.Errorf("unexpected default decision %+v", d)
+	}
+	if v, ok := o.redactor.Value("request.card", "4111111111111111"); !ok || v != "****1111" {
+		t.Errorf("unexpected redacted value %q", v)
+	}
+}
+
+func TestConfigWithoutKeepAlive(t *testing.T) {
+	c := &Config{KeepAlive: &KeepAliveConfig{Disabled: true}}
+	o := evaluateOptions(c.Options())
+	if !o.withOutKeepAliveOpts {
+		t.Error("keepalive should be disabled")
+	}
+
+	var nilConfig *Config
+	o = evaluateOptions(nilConfig.Options())
+	if o.withOutKeepAliveOpts || o.maxSendMsgSize != MaxSendMsgSize {
+		t.Error("nil config should keep the defaults")
+	}
+}
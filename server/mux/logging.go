@@ -0,0 +1,54 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/goriller/ginny/interceptor/logging"
+	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultRedactor the redactor of the response payload if WithRedactor is not set
+var defaultRedactor = logging.NewRedactor()
+
+// annotateMethod record the gRPC full method of the route as the `action` tag of the access log,
+// the same as the gRPC logs, so the decider is called with the same method for both.
+func annotateMethod(ctx context.Context, _ *http.Request) metadata.MD {
+	if method, ok := runtime.RPCMethod(ctx); ok {
+		tags.Extract(ctx).Set("action", method)
+	}
+	return nil
+}
+
+// responseLogging add the fields extracted from the response and the response payload
+// to the tags of the access log.
+func (o *MuxOption) responseLogging(ctx context.Context, _ http.ResponseWriter, m proto.Message) error {
+	method, ok := runtime.RPCMethod(ctx)
+	if !ok {
+		return nil
+	}
+	var decision logging.PayloadDecision
+	if o.loggingDecider != nil {
+		decision = o.loggingDecider(method, nil)
+	}
+	t := tags.Extract(ctx)
+	if o.responseExtractor != nil {
+		for k, v := range o.responseExtractor(method, m) {
+			if decision.Response && k == "content" {
+				continue
+			}
+			t.Set("response_"+k, v)
+		}
+	}
+	if decision.Response {
+		r := o.redactor
+		if r == nil {
+			r = defaultRedactor
+		}
+		t.Set("response_content", r.Payload(m, decision.ClearBytes))
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ type MuxOption struct {
 	logger            grpc_logging.Logger
 	tracer            opentracing.Tracer
 	limiter           *limit.Limiter
+	responseCache     *interceptor.ResponseCache
 	bodyMarshaler     runtime.Marshaler
 	bodyWriter        rewriter.BodyReWriterFunc
 	errorMarshaler    runtime.Marshaler
@@ -28,6 +29,10 @@ type MuxOption struct {
 	runTimeOpts       []runtime.ServeMuxOption
 	withoutHTTPStatus bool
 	middleWares       []middleware.MuxMiddleware
+	redactor          *logging.Redactor
+	loggingDecider    logging.Decider
+	levelFunc         grpc_logging.CodeToLevel
+	responseExtractor logging.ResponseFieldExtractorFunc
 }
 
 var (
@@ -113,6 +118,42 @@ func WithoutHTTPStatus() Optional {
 	}
 }
 
+// WithHTTPStatus pluggable function that performs use http status on response.
+func WithHTTPStatus() Optional {
+	return func(o *MuxOption) {
+		o.withoutHTTPStatus = false
+	}
+}
+
+// WithRedactor mask the sensitive tags and query parameters of the access logs.
+func WithRedactor(r *logging.Redactor) Optional {
+	return func(o *MuxOption) {
+		o.redactor = r
+	}
+}
+
+// WithLoggingDecider decide whether the request is logged and whether the response payload is logged,
+// by the gRPC full method of the route.
+func WithLoggingDecider(decider logging.Decider) Optional {
+	return func(o *MuxOption) {
+		o.loggingDecider = decider
+	}
+}
+
+// WithLevels map the gRPC code of the response to the level of the access log.
+func WithLevels(f grpc_logging.CodeToLevel) Optional {
+	return func(o *MuxOption) {
+		o.levelFunc = f
+	}
+}
+
+// WithResponseFieldExtractor add the fields extracted from the response message to the access log.
+func WithResponseFieldExtractor(f logging.ResponseFieldExtractorFunc) Optional {
+	return func(o *MuxOption) {
+		o.responseExtractor = f
+	}
+}
+
 // WithTracer
 func WithTracer(tracer opentracing.Tracer) Optional {
 	return func(o *MuxOption) {
@@ -129,6 +170,14 @@ func WithLimiter(l *limit.Limiter) Optional {
 	}
 }
 
+// WithResponseCache serves the GET responses of the cached methods from the cache,
+// with ETag and Cache-Control headers.
+func WithResponseCache(rc *interceptor.ResponseCache) Optional {
+	return func(o *MuxOption) {
+		o.responseCache = rc
+	}
+}
+
 // WithAuthFunc
 func WithAuthFunc(a interceptor.Authorize) Optional {
 	return func(o *MuxOption) {
@@ -162,6 +211,8 @@ func fullOptions(logger *zap.Logger,
 	o.middleWares = append(o.middleWares,
 		middleware.TracerMiddleWare(o.tracer))
 	// }
+	o.middleWares = append(o.middleWares,
+		middleware.LoggerMiddleWare(logger))
 
 	// limiter
 	if o.limiter != nil {
@@ -185,6 +236,14 @@ func fullOptions(logger *zap.Logger,
 		runtime.WithErrorHandler(o.errorHandler),
 		runtime.WithMarshalerOption(runtime.MIMEWildcard, o.bodyMarshaler),
 		runtime.WithForwardResponseOption(forwardResponseOptionFunc),
+		runtime.WithMetadata(annotateMethod),
+	}
+	if o.loggingDecider != nil || o.responseExtractor != nil {
+		runtimeOpt = append(runtimeOpt, runtime.WithForwardResponseOption(o.responseLogging))
+	}
+	// response cache, after the route is matched
+	if o.responseCache != nil {
+		runtimeOpt = append(runtimeOpt, runtime.WithMiddlewares(middleware.CacheMiddleWare(o.responseCache)))
 	}
 	o.runTimeOpts = append(o.runTimeOpts, runtimeOpt...)
 
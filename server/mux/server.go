@@ -25,9 +25,16 @@ func NewMuxServe(logger *zap.Logger, opts ...Optional) *MuxServe {
 	}
 	mux.serveMux = runtime.NewServeMux(o.runTimeOpts...)
 
+	logOpts := []middleware.LogOption{
+		middleware.WithLogDecider(o.loggingDecider),
+		middleware.WithLogLevels(o.levelFunc),
+	}
+	if o.redactor != nil {
+		logOpts = append(logOpts, middleware.WithLogRedactor(o.redactor))
+	}
 	// default middleWares
 	var middlewares = []middleware.MuxMiddleware{
-		middleware.RecoverMiddleWare(o.logger, o.bodyMarshaler, o.errorMarshaler, o.withoutHTTPStatus),
+		middleware.RecoverMiddleWare(o.logger, o.bodyMarshaler, o.errorMarshaler, o.withoutHTTPStatus, logOpts...),
 		health.HealthMiddleware,
 	}
 	if len(o.middleWares) > 0 {
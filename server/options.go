@@ -12,6 +12,7 @@ import (
 	"github.com/goriller/ginny/interceptor/limit"
 	"github.com/goriller/ginny/interceptor/logging"
 	"github.com/goriller/ginny/interceptor/tags"
+	"github.com/goriller/ginny/server/admin"
 	"github.com/goriller/ginny/server/mux"
 	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
@@ -55,14 +56,24 @@ type options struct {
 	authFunc                   interceptor.Authorize
 	logger                     grpc_logging.Logger
 	loggingDecider             logging.Decider
+	levelFunc                  grpc_logging.CodeToLevel
 	limiter                    *limit.Limiter
+	responseCache              *interceptor.ResponseCache
 	grpcServerOpts             []grpc.ServerOption
 	withOutKeepAliveOpts       bool
+	keepAliveParams            keepalive.ServerParameters
+	keepAlivePolicy            keepalive.EnforcementPolicy
+	maxSendMsgSize             int
+	maxRecvMsgSize             int
+	initialWindowSize          int32
+	initialConnWindowSize      int32
 	muxOptions                 []mux.Optional
+	adminOptions               []admin.Option
 	streamServerInterceptors   []grpc.StreamServerInterceptor
 	unaryServerInterceptors    []grpc.UnaryServerInterceptor
 	requestFieldExtractorFunc  logging.RequestFieldExtractorFunc
 	responseFieldExtractorFunc logging.ResponseFieldExtractorFunc
+	redactor                   *logging.Redactor
 }
 
 // Discover service discovery
@@ -76,10 +87,22 @@ var defaultOptions = &options{
 	httpAddr:                 ":8080",
 	metricsAddr:              ":8081",
 	tags:                     []string{},
+	maxSendMsgSize:           MaxSendMsgSize,
+	maxRecvMsgSize:           MaxRecvMsgSize,
+	initialWindowSize:        InitialWindowSize,
+	initialConnWindowSize:    InitialConnWindowSize,
 	muxOptions:               []mux.Optional{},
 	grpcServerOpts:           []grpc.ServerOption{},
 	streamServerInterceptors: []grpc.StreamServerInterceptor{},
 	unaryServerInterceptors:  []grpc.UnaryServerInterceptor{},
+	keepAliveParams: keepalive.ServerParameters{
+		MaxConnectionAge: time.Minute,
+		Time:             time.Second * 10,
+		Timeout:          time.Second * 3,
+	},
+	keepAlivePolicy: keepalive.EnforcementPolicy{
+		PermitWithoutStream: true,
+	},
 }
 
 // Option the option for this module
@@ -164,6 +187,21 @@ func WithMetricsAddr(addr string) Option {
 	}
 }
 
+// WithAdmin set the options of the admin handler served on the metrics address,
+// such as admin.WithAuthFunc to guard pprof, channelz and the log level.
+func WithAdmin(opts ...admin.Option) Option {
+	return func(o *options) {
+		o.adminOptions = append(o.adminOptions, opts...)
+	}
+}
+
+// WithRedactor mask the sensitive fields of the payload logs and the gateway access logs.
+func WithRedactor(r *logging.Redactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
+
 // WithDiscover
 func WithDiscover(d Discover, tags ...string) Option {
 	return func(o *options) {
@@ -194,6 +232,16 @@ func WithLoggingDecider(decider logging.Decider) Option {
 	}
 }
 
+// WithLevels customizes the function for mapping gRPC return codes to the log levels,
+// of both the gRPC logs and the gateway access logs.
+func WithLevels(f grpc_logging.CodeToLevel) Option {
+	return func(o *options) {
+		if f != nil {
+			o.levelFunc = f
+		}
+	}
+}
+
 // WithLimiter performs rate limiting on the request.
 func WithLimiter(l *limit.Limiter) Option {
 	return func(o *options) {
@@ -201,6 +249,14 @@ func WithLimiter(l *limit.Limiter) Option {
 	}
 }
 
+// WithResponseCache caches the responses of the configured read-only methods,
+// for both the gRPC server and the gateway.
+func WithResponseCache(rc *interceptor.ResponseCache) Option {
+	return func(o *options) {
+		o.responseCache = rc
+	}
+}
+
 // WithAuthFunc
 func WithAuthFunc(a interceptor.Authorize) Option {
 	return func(o *options) {
@@ -264,6 +320,46 @@ func WithHttpServerOption(opts ...mux.Optional) Option {
 	}
 }
 
+// WithMaxMsgSize set max gRPC message size the server can send and receive.
+func WithMaxMsgSize(send, recv int) Option {
+	return func(o *options) {
+		if send > 0 {
+			o.maxSendMsgSize = send
+		}
+		if recv > 0 {
+			o.maxRecvMsgSize = recv
+		}
+	}
+}
+
+// WithWindowSize set the stream and connection window size of gRPC transport.
+func WithWindowSize(stream, conn int32) Option {
+	return func(o *options) {
+		if stream > 0 {
+			o.initialWindowSize = stream
+		}
+		if conn > 0 {
+			o.initialConnWindowSize = conn
+		}
+	}
+}
+
+// WithKeepAlive set keepalive parameters and enforcement policy for the gRPC server.
+func WithKeepAlive(params keepalive.ServerParameters, policy keepalive.EnforcementPolicy) Option {
+	return func(o *options) {
+		o.withOutKeepAliveOpts = false
+		o.keepAliveParams = params
+		o.keepAlivePolicy = policy
+	}
+}
+
+// WithoutKeepAlive disable the keepalive options, gRPC will use its own defaults.
+func WithoutKeepAlive() Option {
+	return func(o *options) {
+		o.withOutKeepAliveOpts = true
+	}
+}
+
 // fullOptions
 func fullOptions(logger *zap.Logger,
 	opts ...Option) (opt *options) {
@@ -272,28 +368,36 @@ func fullOptions(logger *zap.Logger,
 		opt.logger = logging.InterceptorLogger(logger)
 	}
 
-	muxLoggingOpts := []grpc_logging.Option{}
-	grpc_logging.UnaryClientInterceptor(opt.logger)
-
-	// Create options for local logging package
+	// the logging options are shared by the gRPC logs and the gateway access logs
 	localLoggingOpts := []logging.Option{}
-	// if opt.loggingDecider != nil {
-	// 	localLoggingOpts = append(localLoggingOpts,
-	// 		logging.WithDecider(opt.loggingDecider))
-	// }
-	// if opt.requestFieldExtractorFunc != nil {
-	// 	localLoggingOpts = append(localLoggingOpts,
-	// 		logging.WithRequestFieldExtractorFunc(opt.requestFieldExtractorFunc))
-	// }
-	// if opt.responseFieldExtractorFunc != nil {
-	// 	localLoggingOpts = append(localLoggingOpts,
-	// 		logging.WithResponseFieldExtractorFunc(opt.responseFieldExtractorFunc))
-	// }
+	if opt.redactor != nil {
+		localLoggingOpts = append(localLoggingOpts, logging.WithRedactor(opt.redactor))
+		opt.muxOptions = append(opt.muxOptions, mux.WithRedactor(opt.redactor))
+	}
+	if opt.loggingDecider != nil {
+		localLoggingOpts = append(localLoggingOpts,
+			logging.WithDecider(opt.loggingDecider))
+		opt.muxOptions = append(opt.muxOptions, mux.WithLoggingDecider(opt.loggingDecider))
+	}
+	if opt.levelFunc != nil {
+		localLoggingOpts = append(localLoggingOpts,
+			logging.WithLevels(opt.levelFunc))
+		opt.muxOptions = append(opt.muxOptions, mux.WithLevels(opt.levelFunc))
+	}
+	// the gateway calls the services in process, so the request message is not available to it
+	if opt.requestFieldExtractorFunc != nil {
+		localLoggingOpts = append(localLoggingOpts,
+			logging.WithRequestFieldExtractorFunc(opt.requestFieldExtractorFunc))
+	}
+	if opt.responseFieldExtractorFunc != nil {
+		localLoggingOpts = append(localLoggingOpts,
+			logging.WithResponseFieldExtractorFunc(opt.responseFieldExtractorFunc))
+		opt.muxOptions = append(opt.muxOptions, mux.WithResponseFieldExtractor(opt.responseFieldExtractorFunc))
+	}
 
 	unaryServerInterceptors := []grpc.UnaryServerInterceptor{
 		tags.UnaryServerInterceptor(),
 		grpc_prometheus.UnaryServerInterceptor,
-		grpc_logging.UnaryServerInterceptor(opt.logger, muxLoggingOpts...),
 		logging.UnaryServerInterceptor(
 			opt.logger,
 			localLoggingOpts...,
@@ -319,6 +423,11 @@ func fullOptions(logger *zap.Logger,
 	streamServerInterceptors = append(streamServerInterceptors,
 		interceptor.TracerServerStreamInterceptor(opt.tracer))
 	// }
+	// the request scoped logger with the request_id and trace ids
+	unaryServerInterceptors = append(unaryServerInterceptors,
+		interceptor.LoggerUnaryServerInterceptor(logger))
+	streamServerInterceptors = append(streamServerInterceptors,
+		interceptor.LoggerStreamServerInterceptor(logger))
 	// limiter
 	if opt.limiter != nil {
 		opt.muxOptions = append(opt.muxOptions, mux.WithLimiter(opt.limiter))
@@ -337,6 +446,13 @@ func fullOptions(logger *zap.Logger,
 			interceptor.AuthStreamServerInterceptor(opt.authFunc))
 	}
 
+	// response cache, after auth so that only authorized requests are served from the cache
+	if opt.responseCache != nil {
+		opt.muxOptions = append(opt.muxOptions, mux.WithResponseCache(opt.responseCache))
+		unaryServerInterceptors = append(unaryServerInterceptors,
+			interceptor.CacheUnaryServerInterceptor(opt.responseCache))
+	}
+
 	if len(opt.unaryServerInterceptors) > 0 {
 		unaryServerInterceptors = append(unaryServerInterceptors, opt.unaryServerInterceptors...)
 	}
@@ -358,25 +474,16 @@ func fullOptions(logger *zap.Logger,
 	opt.grpcServerOpts = append(opt.grpcServerOpts,
 		grpc.ChainStreamInterceptor(streamServerInterceptors...),
 		grpc.ChainUnaryInterceptor(unaryServerInterceptors...),
-		grpc.MaxSendMsgSize(MaxSendMsgSize),
-		grpc.MaxRecvMsgSize(MaxRecvMsgSize),
-		grpc.InitialWindowSize(InitialWindowSize),
-		grpc.InitialConnWindowSize(InitialConnWindowSize),
+		grpc.MaxSendMsgSize(opt.maxSendMsgSize),
+		grpc.MaxRecvMsgSize(opt.maxRecvMsgSize),
+		grpc.InitialWindowSize(opt.initialWindowSize),
+		grpc.InitialConnWindowSize(opt.initialConnWindowSize),
 	)
 
 	if !opt.withOutKeepAliveOpts {
 		opt.grpcServerOpts = append(opt.grpcServerOpts,
-			grpc.KeepaliveParams(
-				keepalive.ServerParameters{
-					MaxConnectionAge: time.Minute,
-					Time:             time.Second * 10,
-					Timeout:          time.Second * 3,
-				}),
-			grpc.KeepaliveEnforcementPolicy(
-				keepalive.EnforcementPolicy{
-					PermitWithoutStream: true,
-				},
-			),
+			grpc.KeepaliveParams(opt.keepAliveParams),
+			grpc.KeepaliveEnforcementPolicy(opt.keepAlivePolicy),
 		)
 	}
 
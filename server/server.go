@@ -6,16 +6,17 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/goriller/ginny-util/graceful"
+	"github.com/goriller/ginny/server/admin"
 	"github.com/goriller/ginny/server/health"
 	"github.com/goriller/ginny/server/mux"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/pkg/errors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
@@ -29,6 +30,11 @@ type Server struct {
 	options *options
 	mux     *mux.MuxServe
 
+	// ready closed when all the listeners are bound
+	ready     chan struct{}
+	listening sync.WaitGroup
+	closeOnce sync.Once
+
 	grpcServer    *grpc.Server
 	httpServer    *http.Server
 	metricsServer *http.Server
@@ -43,6 +49,7 @@ func NewServer(ctx context.Context, logger *zap.Logger, opts ...Option) *Server
 		logger:  opt.logger,
 		options: opt,
 		locker:  &sync.Mutex{},
+		ready:   make(chan struct{}),
 	}
 	svc.grpcServer = grpc.NewServer(opt.grpcServerOpts...)
 	if opt.httpAddr != "" {
@@ -50,8 +57,13 @@ func NewServer(ctx context.Context, logger *zap.Logger, opts ...Option) *Server
 		svc.httpServer = &http.Server{Addr: opt.httpAddr, Handler: svc.mux}
 	}
 
+	// the admin endpoints are never served on the gateway port
+	if opt.metricsAddr != "" && opt.httpAddr != "" && sameListenAddr(opt.metricsAddr, opt.httpAddr) {
+		opt.logger.Log(ctx, logging.LevelError, "metrics address is the same as http address, the admin server is disabled")
+		opt.metricsAddr = ""
+	}
 	if opt.metricsAddr != "" {
-		svc.metricsServer = &http.Server{Addr: opt.metricsAddr, Handler: promhttp.Handler()}
+		svc.metricsServer = &http.Server{Addr: opt.metricsAddr, Handler: admin.NewHandler(opt.adminOptions...)}
 	}
 
 	svc.healthServer = health.NewHealthServer()
@@ -59,6 +71,42 @@ func NewServer(ctx context.Context, logger *zap.Logger, opts ...Option) *Server
 	return svc
 }
 
+// sameListenAddr report whether the listen addresses share the port, such as ":8080" and "0.0.0.0:8080"
+func sameListenAddr(a, b string) bool {
+	ha, pa, err := net.SplitHostPort(a)
+	if err != nil {
+		return a == b
+	}
+	hb, pb, err := net.SplitHostPort(b)
+	if err != nil {
+		return a == b
+	}
+	na, errA := net.LookupPort("tcp", pa)
+	nb, errB := net.LookupPort("tcp", pb)
+	if errA != nil || errB != nil {
+		return pa == pb && ha == hb
+	}
+	if na != nb {
+		return false
+	}
+	ipA, ipB := listenIP(ha), listenIP(hb)
+	if ipA == nil || ipB == nil {
+		return strings.EqualFold(ha, hb)
+	}
+	return ipA.IsUnspecified() || ipB.IsUnspecified() || ipA.Equal(ipB)
+}
+
+// listenIP the ip of the listen host, the empty host listens on all interfaces
+func listenIP(host string) net.IP {
+	switch strings.ToLower(host) {
+	case "":
+		return net.IPv4zero
+	case "localhost":
+		return net.IPv4(127, 0, 0, 1)
+	}
+	return net.ParseIP(host)
+}
+
 // Start
 func (s *Server) Start(ctx context.Context) {
 	graceful.AddCloser(s.Close)
@@ -80,10 +128,26 @@ func (s *Server) Start(ctx context.Context) {
 			return s.register(ctx)
 		})
 	}
+	s.listening.Add(1)
+	if s.httpServer != nil {
+		s.listening.Add(1)
+	}
+	if s.metricsServer != nil {
+		s.listening.Add(1)
+	}
+	go func() {
+		s.listening.Wait()
+		close(s.ready)
+	}()
 
 	graceful.Start(fns...)
 }
 
+// Ready return the channel closed when all the listeners are bound.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
 // startGRPC
 func (s *Server) startGRPC(ctx context.Context) error {
 	lis, err := net.Listen("tcp", s.options.grpcAddr)
@@ -92,6 +156,7 @@ func (s *Server) startGRPC(ctx context.Context) error {
 		return err
 	}
 	s.healthServer.Start(s.grpcServer)
+	s.listening.Done()
 
 	s.logger.Log(ctx, logging.LevelInfo, "Start grpc at "+s.options.grpcAddr)
 	if err := s.grpcServer.Serve(lis); err != nil {
@@ -105,8 +170,13 @@ func (s *Server) startHTTP(ctx context.Context) error {
 	if s.httpServer == nil {
 		return nil
 	}
+	lis, err := net.Listen("tcp", s.options.httpAddr)
+	if err != nil {
+		return errors.New("listen http failed for " + err.Error())
+	}
+	s.listening.Done()
 	s.logger.Log(ctx, logging.LevelInfo, "start http at "+s.options.httpAddr)
-	err := s.httpServer.ListenAndServe()
+	err = s.httpServer.Serve(lis)
 	if !errors.Is(err, http.ErrServerClosed) {
 		return errors.New("start http failed for " + err.Error())
 	}
@@ -117,8 +187,13 @@ func (s *Server) startMetrics(ctx context.Context) error {
 	if s.metricsServer == nil {
 		return nil
 	}
+	lis, err := net.Listen("tcp", s.options.metricsAddr)
+	if err != nil {
+		return errors.New("listen metrics failed for " + err.Error())
+	}
+	s.listening.Done()
 	s.logger.Log(ctx, logging.LevelInfo, "start metrics at "+s.options.metricsAddr)
-	err := s.metricsServer.ListenAndServe()
+	err = s.metricsServer.Serve(lis)
 	if !errors.Is(err, http.ErrServerClosed) {
 		return errors.New("start metrics failed for " + err.Error())
 	}
@@ -141,7 +216,17 @@ func (s *Server) RegisterService(ctx context.Context, desc *grpc.ServiceDesc, se
 // Close
 // K8s closes after 60 seconds by default
 // refer: https://kubernetes.io/docs/concepts/containers/container-lifecycle-hooks/
+// It is safe to call Close more than once, only the first call takes effect.
 func (s *Server) Close(ctx context.Context) error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.close(ctx)
+	})
+	return err
+}
+
+// close
+func (s *Server) close(ctx context.Context) error {
 	if s.httpServer != nil {
 		s.httpServer.SetKeepAlivesEnabled(false)
 	}
@@ -0,0 +1,24 @@
+package server
+
+import "testing"
+
+func TestSameListenAddr(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{":8080", ":8080", true},
+		{":8080", "0.0.0.0:8080", true},
+		{"127.0.0.1:8080", ":8080", true},
+		{"[::]:8080", "127.0.0.1:8080", true},
+		{"localhost:8080", "127.0.0.1:8080", true},
+		{"127.0.0.1:8081", ":8080", false},
+		{"127.0.0.1:8080", "10.0.0.1:8080", false},
+		{":http", ":80", true},
+	}
+	for _, tt := range tests {
+		if got := sameListenAddr(tt.a, tt.b); got != tt.same {
+			t.Errorf("sameListenAddr(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package ginny
+
+import (
+	"context"
+
+	"github.com/goriller/ginny/worker"
+)
+
+// AddWorker add the long running worker, it starts after the components and stops before them.
+func (a *Application) AddWorker(name string, fn worker.Job, opts ...worker.Option) *Application {
+	a.workerManager().Add(name, fn, opts...)
+	return a
+}
+
+// Schedule add the job run by the cron spec, see worker.ParseSchedule.
+func (a *Application) Schedule(name, spec string, fn worker.Job, opts ...worker.Option) error {
+	return a.workerManager().Schedule(name, spec, fn, opts...)
+}
+
+// workerManager
+func (a *Application) workerManager() *worker.Manager {
+	if a.workers == nil {
+		a.workers = worker.NewManager(worker.WithLogger(a.Logger))
+	}
+	return a.workers
+}
+
+// startWorkers
+func (a *Application) startWorkers(ctx context.Context) error {
+	if a.workers == nil {
+		return nil
+	}
+	return a.workers.Start(ctx)
+}
+
+// stopWorkers
+func (a *Application) stopWorkers(ctx context.Context) error {
+	if a.workers == nil {
+		return nil
+	}
+	return a.workers.Stop(ctx)
+}
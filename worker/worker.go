@@ -0,0 +1,352 @@
+// Package worker run the background workers and scheduled jobs with panic recovery,
+// restart policies, metrics and tracing, stopped gracefully with the application.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Job the job run by worker, it should return when the ctx is done.
+type Job func(ctx context.Context) error
+
+// RestartPolicy the policy to restart the worker when the job returned.
+type RestartPolicy int
+
+const (
+	// RestartOnFailure restart the worker when the job returns error or panics.
+	RestartOnFailure RestartPolicy = iota
+	// RestartAlways restart the worker whenever the job returns.
+	RestartAlways
+	// RestartNever never restart the worker.
+	RestartNever
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+	resultPanic   = "panic"
+	resultSkipped = "skipped"
+)
+
+var (
+	runsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ginny_worker_runs_total",
+		Help: "Total number of worker and scheduled job runs by result.",
+	}, []string{"name", "result"})
+	runDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ginny_worker_run_duration_seconds",
+		Help:    "Duration of worker and scheduled job runs.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 4, 10),
+	}, []string{"name"})
+)
+
+func init() {
+	prometheus.MustRegister(runsTotal, runDuration)
+}
+
+// options the options of job
+type options struct {
+	restart    RestartPolicy
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	timeout    time.Duration
+	singleRun  bool
+	tracer     opentracing.Tracer
+	logger     *zap.Logger
+}
+
+// Option the option of job
+type Option func(*options)
+
+// WithRestart set the restart policy of worker, default RestartOnFailure.
+func WithRestart(p RestartPolicy) Option {
+	return func(o *options) {
+		o.restart = p
+	}
+}
+
+// WithBackoff set the exponential backoff between restarts, default 1s to 1m.
+func WithBackoff(min, max time.Duration) Option {
+	return func(o *options) {
+		if min > 0 {
+			o.minBackoff = min
+		}
+		if max >= o.minBackoff {
+			o.maxBackoff = max
+		}
+	}
+}
+
+// WithTimeout set the timeout of each run.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithSingleRun skip the scheduled run if the previous one is still running.
+func WithSingleRun() Option {
+	return func(o *options) {
+		o.singleRun = true
+	}
+}
+
+// WithTracer set the tracer of the run spans, default the global tracer.
+func WithTracer(t opentracing.Tracer) Option {
+	return func(o *options) {
+		if t != nil {
+			o.tracer = t
+		}
+	}
+}
+
+// WithLogger set the logger.
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		if l != nil {
+			o.logger = l
+		}
+	}
+}
+
+// job the registered job
+type job struct {
+	name     string
+	fn       Job
+	schedule Schedule
+	opts     *options
+	running  atomic.Bool
+}
+
+// Manager manage the workers and scheduled jobs.
+type Manager struct {
+	mu      sync.Mutex
+	opts    []Option
+	jobs    []*job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// NewManager new manager, the options are the defaults of all jobs.
+func NewManager(opts ...Option) *Manager {
+	return &Manager{opts: opts}
+}
+
+// evaluateOptions
+func (m *Manager) evaluateOptions(opts []Option) *options {
+	o := &options{
+		restart:    RestartOnFailure,
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+		logger:     zap.NewNop(),
+	}
+	for _, fn := range m.opts {
+		fn(o)
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// Add add the long running worker, it starts with the manager.
+func (m *Manager) Add(name string, fn Job, opts ...Option) {
+	m.add(&job{name: name, fn: fn, opts: m.evaluateOptions(opts)})
+}
+
+// Schedule add the job run by the cron spec, see ParseSchedule.
+func (m *Manager) Schedule(name, spec string, fn Job, opts ...Option) error {
+	s, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	m.add(&job{name: name, fn: fn, schedule: s, opts: m.evaluateOptions(opts)})
+	return nil
+}
+
+// add
+func (m *Manager) add(j *job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs = append(m.jobs, j)
+	if m.ctx != nil && !m.stopped {
+		m.launch(j)
+	}
+}
+
+// Start start all the jobs, the jobs added after are started immediately.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx != nil {
+		return nil
+	}
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	for _, j := range m.jobs {
+		m.launch(j)
+	}
+	return nil
+}
+
+// Stop cancel all the jobs and wait for them to return until the ctx is done.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if m.cancel == nil || m.stopped {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopped = true
+	m.cancel()
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for jobs to stop: %w", ctx.Err())
+	}
+}
+
+// launch
+func (m *Manager) launch(j *job) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if j.schedule != nil {
+			m.runSchedule(m.ctx, j)
+		} else {
+			m.runWorker(m.ctx, j)
+		}
+	}()
+}
+
+// runWorker run the worker with the restart policy.
+func (m *Manager) runWorker(ctx context.Context, j *job) {
+	backoff := j.opts.minBackoff
+	for {
+		start := time.Now()
+		err := m.run(ctx, j)
+		if ctx.Err() != nil {
+			return
+		}
+		if j.opts.restart == RestartNever || (err == nil && j.opts.restart == RestartOnFailure) {
+			return
+		}
+		// a long healthy run resets the backoff
+		if err == nil || time.Since(start) > j.opts.maxBackoff {
+			backoff = j.opts.minBackoff
+		}
+		wait := jitter(backoff)
+		if err != nil {
+			backoff *= 2
+			if backoff > j.opts.maxBackoff {
+				backoff = j.opts.maxBackoff
+			}
+		}
+		j.opts.logger.Info("restart worker", zap.String("worker", j.name), zap.Duration("after", wait))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// runSchedule run the job at the schedule times.
+func (m *Manager) runSchedule(ctx context.Context, j *job) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if j.opts.singleRun && !j.running.CompareAndSwap(false, true) {
+			runsTotal.WithLabelValues(j.name, resultSkipped).Inc()
+			j.opts.logger.Warn("skip job, the previous run is still running", zap.String("job", j.name))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if j.opts.singleRun {
+				defer j.running.Store(false)
+			}
+			_ = m.run(ctx, j)
+		}()
+	}
+}
+
+// run run the job once with recovery, metrics and tracing.
+func (m *Manager) run(ctx context.Context, j *job) (err error) {
+	tracer := j.opts.tracer
+	if tracer == nil {
+		tracer = opentracing.GlobalTracer()
+	}
+	span := tracer.StartSpan("worker/"+j.name, opentracing.Tag{Key: string(ext.Component), Value: "worker"})
+	ctx = opentracing.ContextWithSpan(ctx, span)
+	if j.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.opts.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := resultSuccess
+	defer func() {
+		if r := recover(); r != nil {
+			result = resultPanic
+			err = fmt.Errorf("panic: %v", r)
+			j.opts.logger.Error("job panic", zap.String("job", j.name),
+				zap.Any("panic", r), zap.Stack("stacktrace"))
+		} else if err != nil {
+			result = resultFailure
+			j.opts.logger.Error("job failed", zap.String("job", j.name), zap.Error(err))
+		}
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.SetTag("error.message", err.Error())
+		}
+		span.Finish()
+		runsTotal.WithLabelValues(j.name, result).Inc()
+		runDuration.WithLabelValues(j.name).Observe(time.Since(start).Seconds())
+	}()
+
+	return j.fn(ctx)
+}
+
+// jitter add up to 20% random jitter.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}